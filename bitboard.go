@@ -217,3 +217,11 @@ func (b Bitboard) PopFirstSq() (Square, Bitboard) {
 	b ^= BBWithSquares(sq)
 	return sq, b
 }
+
+// LastSq returns the square of the most significant bit in the bitboard
+func (b Bitboard) LastSq() Square {
+	if b == 0 {
+		return NULL_SQ
+	}
+	return Square(63 - bits.LeadingZeros64(uint64(b)))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// parseMainFlags handles the flags given before any subcommand, which apply to the UCI loop
+// and to the subcommands alike, and returns the remaining arguments
+func parseMainFlags(args []string) ([]string, error) {
+	flags := flag.NewFlagSet("Mila", flag.ContinueOnError)
+	evalFile := flags.String("evalfile", "", "a JSON file of eval params to use in place of the defaults")
+	flags.Usage = func() {
+		fmt.Println("Usage: Mila [flags] [perft | tune] ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if *evalFile != "" {
+		params, loadErr := LoadEvalParams(*evalFile)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		SetEvalParams(params)
+	}
+	return flags.Args(), nil
+}
+
+// runCli runs the subcommand given on the command line, in place of the UCI loop
+func runCli(args []string) error {
+	switch args[0] {
+	case "perft":
+		return runPerftCli(args[1:])
+	case "tune":
+		return runTuneCli(args[1:])
+	}
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+func runPerftCli(args []string) error {
+	flags := flag.NewFlagSet("perft", flag.ContinueOnError)
+	fen := flags.String("fen", InitPos().FEN(), "the position to count from")
+	threads := flags.Int("threads", runtime.NumCPU(), "the number of threads to split the root moves across")
+	hashMb := flags.Int("hash", 0, "the size in megabytes of the table caching transposed positions, 0 for none")
+	flags.Usage = func() {
+		fmt.Println("Usage: Mila perft [flags] {depth}")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a single depth argument")
+	}
+	depth, parseErr := strconv.Atoi(flags.Arg(0))
+	if parseErr != nil || depth < 0 {
+		return fmt.Errorf("could not parse %s as perft depth", flags.Arg(0))
+	}
+	pos, posErr := FromFEN(*fen)
+	if posErr != nil {
+		return posErr
+	}
+	var table *PerftTable
+	if *hashMb > 0 {
+		table = NewPerftTable(*hashMb)
+	}
+	RunPerft(pos, depth, *threads, table)
+	return nil
+}
+
+func runTuneCli(args []string) error {
+	flags := flag.NewFlagSet("tune", flag.ContinueOnError)
+	dataPath := flags.String("data", "", "the dataset of quiet positions, one fen and game result per line")
+	outPath := flags.String("out", "eval_params.json", "the file to write the tuned params to after each pass")
+	passes := flags.Int("passes", 100, "the maximum number of passes over the params")
+	step := flags.Int("step", 1, "how much a weight is nudged at a time")
+	threads := flags.Int("threads", runtime.NumCPU(), "the number of threads to split the dataset across")
+	flags.Usage = func() {
+		fmt.Println("Usage: Mila [-evalfile {params}] tune -data {dataset} [flags]")
+		fmt.Println("Tuning starts from the params of the eval file if one is given, or from the defaults.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dataPath == "" {
+		flags.Usage()
+		return fmt.Errorf("expected a dataset")
+	}
+	if *step < 1 {
+		return fmt.Errorf("step must be positive")
+	}
+	entries, loadErr := LoadTuningDataset(*dataPath)
+	if loadErr != nil {
+		return loadErr
+	}
+	params := evalParams.Copy()
+	fmt.Printf("loaded %d positions, fitting K\n", len(entries))
+	tuner := NewTuner(entries, *threads, params)
+	tuner.Step = int16(*step)
+	tuner.MaxPasses = *passes
+	fmt.Printf("K %.3f, starting error %.6f\n", tuner.K, tuner.Error(params))
+
+	var saveErr error
+	tuner.Tune(params, func(pass int, err float64) {
+		fmt.Printf("pass %d error %.6f\n", pass, err)
+		if writeErr := SaveEvalParams(*outPath, params); writeErr != nil && saveErr == nil {
+			saveErr = writeErr
+		}
+	})
+	if saveErr != nil {
+		return saveErr
+	}
+	fmt.Println("wrote", *outPath)
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"math"
 )
 
+const MAX_MULTI_PV = 64
+const MAX_THREADS = 256
+
 type SearchConstraints struct {
 	moves       []Move
 	whiteMs     int
@@ -13,6 +16,11 @@ type SearchConstraints struct {
 	maxDepth    uint8
 	maxNodes    int
 	maxMs       int
+	infinite    bool
+	ponder      bool
+	multiPV     int
+	threads     int
+	lmrStrength int
 }
 
 func (sc *SearchConstraints) NodeCntLmt() int {
@@ -29,6 +37,28 @@ func (sc *SearchConstraints) DepthLmt() uint8 {
 	return math.MaxUint8
 }
 
+func (sc *SearchConstraints) MultiPV() int {
+	if sc.multiPV > 0 {
+		return sc.multiPV
+	}
+	return 1
+}
+
+func (sc *SearchConstraints) Threads() int {
+	if sc.threads > 0 {
+		return sc.threads
+	}
+	return 1
+}
+
+// LMRStrength scales late move reductions as a percentage of their default size
+func (sc *SearchConstraints) LMRStrength() int {
+	if sc.lmrStrength > 0 {
+		return sc.lmrStrength
+	}
+	return DEFAULT_LMR_STRENGTH
+}
+
 func (sc *SearchConstraints) MaxSearchMs() int {
 	var maxSearchMs = 100_000_000_000
 	if sc.maxMs > 0 {
@@ -36,3 +66,28 @@ func (sc *SearchConstraints) MaxSearchMs() int {
 	}
 	return maxSearchMs
 }
+
+// AllowsRootMove returns false if the move was excluded by searchmoves
+func (sc *SearchConstraints) AllowsRootMove(move Move) bool {
+	if len(sc.moves) == 0 {
+		return true
+	}
+	for _, allowedMove := range sc.moves {
+		if move == allowedMove {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterRootMoves removes the moves excluded by searchmoves, if any were given.
+// The filtered moves are written into the given slice.
+func (sc *SearchConstraints) FilterRootMoves(moves []Move) []Move {
+	filtered := moves[:0]
+	for _, move := range moves {
+		if sc.AllowsRootMove(move) {
+			filtered = append(filtered, move)
+		}
+	}
+	return filtered
+}
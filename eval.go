@@ -10,21 +10,112 @@ const (
 	DRAW_VAL   = int16(-50)
 )
 
+const PST_ENABLED = true
+
+// MAX_MATE_PLY is the furthest from the search root that a mate can be scored.
+// Scores within this many plies of MATE_VAL are mate scores.
+const MAX_MATE_PLY = 512
+
+// MatedIn returns the score for the side to move being checkmated the given number
+// of plies from the root of the search.
+func MatedIn(ply Ply) int16 {
+	return -MATE_VAL + int16(ply)
+}
+
+func IsMateScore(score int16) bool {
+	return score >= MATE_VAL-MAX_MATE_PLY || score <= -MATE_VAL+MAX_MATE_PLY
+}
+
+// MatePlies returns the number of plies until mate for a mate score
+func MatePlies(score int16) int {
+	if score > 0 {
+		return int(MATE_VAL - score)
+	}
+	return int(MATE_VAL + score)
+}
+
+// MateMoves returns the number of moves (not plies) until mate for a mate score, as
+// expected by UCI's "score mate". The number is negative if the side to move is
+// getting mated.
+func MateMoves(score int16) int {
+	if score > 0 {
+		return (MatePlies(score) + 1) / 2
+	}
+	return -MatePlies(score) / 2
+}
+
+// EvalPos scores the position from the side to move's point of view, evaluating the pawn
+// structure from scratch
 func EvalPos(pos *Position) int16 {
+	return EvalPosCached(pos, nil)
+}
+
+// EvalPosCached is EvalPos with the pawn structure looked up in the pawn table, which may
+// be nil
+func EvalPosCached(pos *Position, pawns *PawnTable) int16 {
 	if pos.result != RESULT_IN_PROGRESS {
-		return DRAW_VAL
+		return evalParams.DrawVal
 	}
 	mat := pos.material
-	eval := PAWN_VAL*mat.pawnDiff() + KNIGHT_VAL*mat.knightDiff() + BISHOP_VAL*mat.bishopDiff() +
-		ROOK_VAL*mat.rookDiff() + QUEEN_VAL*mat.queenDiff()
+	params := evalParams
+	materialDiff := params.PawnVal*mat.pawnDiff() + params.KnightVal*mat.knightDiff() +
+		params.BishopVal*mat.bishopDiff() + params.RookVal*mat.rookDiff() + params.QueenVal*mat.queenDiff()
+	score := TaperedScore{materialDiff, materialDiff}
+	if PST_ENABLED {
+		score = score.Add(TaperedScore(pos.pst))
+	}
+	if PAWN_STRUCTURE_ENABLED {
+		entry := pawns.Probe(pos)
+		score = score.Add(TaperedScore{entry.mg, entry.eg})
+		score.eg += PasserKingProximity(pos, entry.passers, WHITE) - PasserKingProximity(pos, entry.passers, BLACK)
+	}
+	if KING_SAFETY_ENABLED {
+		score = score.Add(EvalKingSafety(pos, WHITE)).Sub(EvalKingSafety(pos, BLACK))
+	}
+	if MOBILITY_ENABLED {
+		score = score.Add(EvalMobility(pos, WHITE)).Sub(EvalMobility(pos, BLACK))
+	}
+	if PIECES_ENABLED {
+		score = score.Add(EvalPieces(pos, WHITE)).Sub(EvalPieces(pos, BLACK))
+	}
+	eval := score.Taper(mat.Phase())
 	if pos.isWhiteTurn {
 		return eval
 	} else {
 		return -eval
 	}
+}
+
+// MaterialValue sums the values of the color's pieces
+func MaterialValue(mat *Material, color Color) int16 {
+	offset := 0
+	if color == BLACK {
+		offset = 6
+	}
+	params := evalParams
+	return params.PawnVal*int16(mat[offset]) + params.KnightVal*int16(mat[offset+1]) +
+		params.BishopVal*(int16(mat[offset+2])+int16(mat[offset+3])) + params.RookVal*int16(mat[offset+4]) +
+		params.QueenVal*int16(mat[offset+5])
+}
+
+// TaperedScore is a pair of middlegame and endgame scores, which are blended by the game
+// phase once the whole position has been scored
+type TaperedScore struct {
+	mg int16
+	eg int16
+}
+
+func (s TaperedScore) Add(other TaperedScore) TaperedScore {
+	return TaperedScore{s.mg + other.mg, s.eg + other.eg}
+}
+
+func (s TaperedScore) Sub(other TaperedScore) TaperedScore {
+	return TaperedScore{s.mg - other.mg, s.eg - other.eg}
+}
 
-	// TODO: consider material placement - towards center usually is good
-	// TODO: consider passed pawns
+// Taper blends the middlegame and endgame scores by the game phase
+func (s TaperedScore) Taper(phase int) int16 {
+	return int16((int(s.mg)*phase + int(s.eg)*(MAX_PHASE-phase)) / MAX_PHASE)
 }
 
 func ExpMoves(pos *Position) int {
@@ -118,17 +209,19 @@ func EvalMove(pos *Position, move Move) int16 {
 	return moveVal
 }
 
+// PieceTypeToVal returns the piece value of the eval params, so that exchanges are weighed
+// by the same values that the evaluation counts
 func PieceTypeToVal(pt PieceType) int16 {
 	if pt == PAWN {
-		return PAWN_VAL
+		return evalParams.PawnVal
 	} else if pt == KNIGHT {
-		return KNIGHT_VAL
+		return evalParams.KnightVal
 	} else if pt == BISHOP {
-		return BISHOP_VAL
+		return evalParams.BishopVal
 	} else if pt == ROOK {
-		return ROOK_VAL
+		return evalParams.RookVal
 	} else if pt == QUEEN {
-		return QUEEN_VAL
+		return evalParams.QueenVal
 	}
 	return 0
 }
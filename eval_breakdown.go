@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EvalTerm is one of the independent parts that EvalPos adds up
+type EvalTerm uint8
+
+const (
+	MATERIAL_TERM EvalTerm = iota
+	PST_TERM
+	PAWNS_TERM
+	KING_SAFETY_TERM
+	MOBILITY_TERM
+	PIECES_TERM
+	N_EVAL_TERMS
+)
+
+func (t EvalTerm) String() string {
+	switch t {
+	case MATERIAL_TERM:
+		return "material"
+	case PST_TERM:
+		return "pst"
+	case PAWNS_TERM:
+		return "pawns"
+	case KING_SAFETY_TERM:
+		return "king safety"
+	case MOBILITY_TERM:
+		return "mobility"
+	case PIECES_TERM:
+		return "pieces"
+	default:
+		return "unknown"
+	}
+}
+
+// EvalBreakdown holds every term of a position's evaluation for each side, from that
+// side's point of view. It walks the whole position instead of using anything kept up to
+// date incrementally, so it's meant for inspecting evaluations, not for searching.
+type EvalBreakdown struct {
+	FEN   string
+	Phase int
+	Terms [N_EVAL_TERMS][N_COLORS]TaperedScore
+	// Eval is EvalPos from white's point of view, which is the draw score for games
+	// that are over
+	Eval int16
+}
+
+func NewEvalBreakdown(pos *Position) *EvalBreakdown {
+	b := &EvalBreakdown{FEN: pos.FEN(), Phase: pos.material.Phase(), Eval: EvalPos(pos)}
+	if !pos.isWhiteTurn {
+		b.Eval = -b.Eval
+	}
+	for _, color := range []Color{WHITE, BLACK} {
+		terms := &b.Terms
+		material := MaterialValue(&pos.material, color)
+		terms[MATERIAL_TERM][color] = TaperedScore{material, material}
+		if PST_ENABLED {
+			terms[PST_TERM][color] = ColorPSTScore(pos, color)
+		}
+		if PAWN_STRUCTURE_ENABLED {
+			mg, eg, passers := evalPawns(pos, color)
+			terms[PAWNS_TERM][color] = TaperedScore{mg, eg + PasserKingProximity(pos, passers, color)}
+		}
+		if KING_SAFETY_ENABLED {
+			terms[KING_SAFETY_TERM][color] = EvalKingSafety(pos, color)
+		}
+		if MOBILITY_ENABLED {
+			terms[MOBILITY_TERM][color] = EvalMobility(pos, color)
+		}
+		if PIECES_ENABLED {
+			terms[PIECES_TERM][color] = EvalPieces(pos, color)
+		}
+	}
+	return b
+}
+
+// Score returns the term from white's point of view
+func (b *EvalBreakdown) Score(term EvalTerm) TaperedScore {
+	return b.Terms[term][WHITE].Sub(b.Terms[term][BLACK])
+}
+
+// TotalScore sums every term from white's point of view, before tapering
+func (b *EvalBreakdown) TotalScore() TaperedScore {
+	var total TaperedScore
+	for term := EvalTerm(0); term < N_EVAL_TERMS; term++ {
+		total = total.Add(b.Score(term))
+	}
+	return total
+}
+
+// Total returns the tapered evaluation from white's point of view. It matches Eval for
+// positions that are still in progress.
+func (b *EvalBreakdown) Total() int16 {
+	return b.TotalScore().Taper(b.Phase)
+}
+
+// String lays the breakdown out as a table, with a row per term and the middlegame and
+// endgame scores of each side and of both sides combined
+func (b *EvalBreakdown) String() string {
+	builder := strings.Builder{}
+	row := func(label string, cells ...string) {
+		builder.WriteString(PadToWidth(14, label))
+		for cellIdx, cell := range cells {
+			if cellIdx > 0 && cellIdx%2 == 0 {
+				builder.WriteString("  ")
+			}
+			builder.WriteString(fmt.Sprintf("%7s", cell))
+		}
+		builder.WriteByte('\n')
+	}
+	scoreCells := func(scores ...TaperedScore) []string {
+		cells := make([]string, 0, 2*len(scores))
+		for _, score := range scores {
+			cells = append(cells, fmt.Sprint(score.mg), fmt.Sprint(score.eg))
+		}
+		return cells
+	}
+
+	builder.WriteString(fmt.Sprintf("fen %s\n\n", b.FEN))
+	builder.WriteString(fmt.Sprintf("%s%14s  %14s  %14s\n", PadToWidth(14, ""), "white", "black", "total"))
+	row("term", "mg", "eg", "mg", "eg", "mg", "eg")
+	var whiteTotal, blackTotal TaperedScore
+	for term := EvalTerm(0); term < N_EVAL_TERMS; term++ {
+		white, black := b.Terms[term][WHITE], b.Terms[term][BLACK]
+		whiteTotal, blackTotal = whiteTotal.Add(white), blackTotal.Add(black)
+		row(term.String(), scoreCells(white, black, b.Score(term))...)
+	}
+	row("total", scoreCells(whiteTotal, blackTotal, b.TotalScore())...)
+	builder.WriteByte('\n')
+	builder.WriteString(fmt.Sprintf("phase %d/%d\n", b.Phase, MAX_PHASE))
+	builder.WriteString(fmt.Sprintf("eval %d (white's point of view)\n", b.Eval))
+	return builder.String()
+}
+
+type evalScoreJSON struct {
+	Mg int16 `json:"mg"`
+	Eg int16 `json:"eg"`
+}
+
+type evalTermJSON struct {
+	Name  string        `json:"name"`
+	White evalScoreJSON `json:"white"`
+	Black evalScoreJSON `json:"black"`
+	Total evalScoreJSON `json:"total"`
+}
+
+type evalBreakdownJSON struct {
+	FEN      string         `json:"fen"`
+	Phase    int            `json:"phase"`
+	MaxPhase int            `json:"maxPhase"`
+	Terms    []evalTermJSON `json:"terms"`
+	Total    evalScoreJSON  `json:"total"`
+	Eval     int16          `json:"eval"`
+}
+
+// MarshalJSON encodes the breakdown with the terms listed in order and named, so that
+// breakdowns from different versions can be diffed
+func (b *EvalBreakdown) MarshalJSON() ([]byte, error) {
+	toJSON := func(score TaperedScore) evalScoreJSON {
+		return evalScoreJSON{score.mg, score.eg}
+	}
+	out := evalBreakdownJSON{
+		FEN:      b.FEN,
+		Phase:    b.Phase,
+		MaxPhase: MAX_PHASE,
+		Terms:    make([]evalTermJSON, 0, N_EVAL_TERMS),
+		Total:    toJSON(b.TotalScore()),
+		Eval:     b.Eval,
+	}
+	for term := EvalTerm(0); term < N_EVAL_TERMS; term++ {
+		out.Terms = append(out.Terms, evalTermJSON{
+			Name:  term.String(),
+			White: toJSON(b.Terms[term][WHITE]),
+			Black: toJSON(b.Terms[term][BLACK]),
+			Total: toJSON(b.Score(term)),
+		})
+	}
+	return json.Marshal(out)
+}
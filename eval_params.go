@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// EvalParams holds the weights of the evaluation terms, so that they can be tuned and
+// swapped without touching the code. The piece-square tables are not included, as
+// positions keep their piece-square score up to date incrementally.
+type EvalParams struct {
+	PawnVal   int16
+	KnightVal int16
+	BishopVal int16
+	RookVal   int16
+	QueenVal  int16
+	// DrawVal is the score of a drawn game for the side to move
+	DrawVal int16
+
+	DoubledPawnMg  int16
+	DoubledPawnEg  int16
+	IsolatedPawnMg int16
+	IsolatedPawnEg int16
+	BackwardPawnMg int16
+	BackwardPawnEg int16
+	// The pawn bonuses are indexed by the pawn's relative rank, so the first and last
+	// entries are never used
+	ConnectedPawnMg [N_RANKS + 1]int16
+	ConnectedPawnEg [N_RANKS + 1]int16
+	PassedPawnMg    [N_RANKS + 1]int16
+	PassedPawnEg    [N_RANKS + 1]int16
+	// PasserKingWeight scales how much the distances of the kings to a passed pawn's stop
+	// square matter. Only passers that are well advanced are worth escorting or catching.
+	PasserKingWeight [N_RANKS + 1]int16
+
+	PawnShieldAdvancedMg   int16
+	PawnShieldMissingMg    int16
+	SemiOpenFileNearKingMg int16
+	OpenFileNearKingMg     int16
+	// KingAttackWeights is how dangerous each type of piece is, per square of the king
+	// zone that it attacks
+	KingAttackWeights [N_PIECE_TYPES]int16
+	// KingAttackerScales is the percentage of the attack weight that counts, by the number
+	// of pieces attacking the king zone. A lone attacker rarely gets anywhere.
+	KingAttackerScales [8]int16
+	// PawnStormMg is the penalty for an enemy pawn in front of the king, by how many ranks
+	// ahead of the king it is. Pawns right in front of the king are usually stuck.
+	PawnStormMg [N_RANKS]int16
+
+	// MobilityMg and MobilityEg are the weights per square of mobility, by piece type
+	MobilityMg [N_PIECE_TYPES]int16
+	MobilityEg [N_PIECE_TYPES]int16
+
+	BishopPairMg       int16
+	BishopPairEg       int16
+	RookOpenFileMg     int16
+	RookOpenFileEg     int16
+	RookSemiOpenFileMg int16
+	RookSemiOpenFileEg int16
+	RookOn7thMg        int16
+	RookOn7thEg        int16
+	KnightOutpostMg    int16
+	KnightOutpostEg    int16
+	BishopOutpostMg    int16
+	BishopOutpostEg    int16
+}
+
+// DefaultEvalParams returns the hand-picked weights
+func DefaultEvalParams() *EvalParams {
+	return &EvalParams{
+		PawnVal:   PAWN_VAL,
+		KnightVal: KNIGHT_VAL,
+		BishopVal: BISHOP_VAL,
+		RookVal:   ROOK_VAL,
+		QueenVal:  QUEEN_VAL,
+		DrawVal:   DRAW_VAL,
+
+		DoubledPawnMg:    -10,
+		DoubledPawnEg:    -25,
+		IsolatedPawnMg:   -10,
+		IsolatedPawnEg:   -15,
+		BackwardPawnMg:   -8,
+		BackwardPawnEg:   -10,
+		ConnectedPawnMg:  [N_RANKS + 1]int16{0, 0, 4, 6, 10, 20, 35, 60, 0},
+		ConnectedPawnEg:  [N_RANKS + 1]int16{0, 0, 2, 4, 8, 15, 30, 50, 0},
+		PassedPawnMg:     [N_RANKS + 1]int16{0, 0, 5, 10, 15, 30, 55, 90, 0},
+		PassedPawnEg:     [N_RANKS + 1]int16{0, 0, 10, 20, 35, 60, 100, 150, 0},
+		PasserKingWeight: [N_RANKS + 1]int16{0, 0, 0, 0, 1, 3, 5, 7, 0},
+
+		PawnShieldAdvancedMg:   -10,
+		PawnShieldMissingMg:    -25,
+		SemiOpenFileNearKingMg: -15,
+		OpenFileNearKingMg:     -30,
+		KingAttackWeights:      [N_PIECE_TYPES]int16{KNIGHT: 6, BISHOP: 5, ROOK: 8, QUEEN: 14},
+		KingAttackerScales:     [8]int16{0, 0, 50, 75, 88, 94, 97, 99},
+		PawnStormMg:            [N_RANKS]int16{0, -5, -30, -15, -5, 0, 0, 0},
+
+		MobilityMg: [N_PIECE_TYPES]int16{KNIGHT: 4, BISHOP: 5, ROOK: 2, QUEEN: 1},
+		MobilityEg: [N_PIECE_TYPES]int16{KNIGHT: 4, BISHOP: 5, ROOK: 4, QUEEN: 2},
+
+		BishopPairMg:       30,
+		BishopPairEg:       50,
+		RookOpenFileMg:     25,
+		RookOpenFileEg:     10,
+		RookSemiOpenFileMg: 12,
+		RookSemiOpenFileEg: 6,
+		RookOn7thMg:        10,
+		RookOn7thEg:        25,
+		KnightOutpostMg:    25,
+		KnightOutpostEg:    15,
+		BishopOutpostMg:    12,
+		BishopOutpostEg:    6,
+	}
+}
+
+// evalParams are the weights that the evaluation and the search read. They must not be
+// swapped while a search is running.
+var evalParams = DefaultEvalParams()
+
+// EvalParam is a single named weight of the parameter vector
+type EvalParam struct {
+	Name  string
+	Value *int16
+	// IsTunable is false for weights that the positions of a tuning dataset can't tell
+	// anything about
+	IsTunable bool
+}
+
+// Vector lists every weight of the params in a stable order. Entries of the tables that
+// can never be read, such as the bonus for a pawn on the 1st rank, are left out.
+func (p *EvalParams) Vector() []EvalParam {
+	vector := make([]EvalParam, 0, 128)
+	add := func(name string, value *int16) {
+		vector = append(vector, EvalParam{name, value, true})
+	}
+	addRanks := func(name string, values []int16, minRank, maxRank int) {
+		for rank := minRank; rank <= maxRank; rank++ {
+			add(fmt.Sprintf("%s[%d]", name, rank), &values[rank])
+		}
+	}
+	addPieceTypes := func(name string, values []int16) {
+		for _, pt := range []PieceType{KNIGHT, BISHOP, ROOK, QUEEN} {
+			add(fmt.Sprintf("%s[%s]", name, pieceTypeName(pt)), &values[pt])
+		}
+	}
+
+	add("PawnVal", &p.PawnVal)
+	add("KnightVal", &p.KnightVal)
+	add("BishopVal", &p.BishopVal)
+	add("RookVal", &p.RookVal)
+	add("QueenVal", &p.QueenVal)
+	// tuning positions are taken from games in progress, which are never scored as draws
+	vector = append(vector, EvalParam{"DrawVal", &p.DrawVal, false})
+
+	add("DoubledPawnMg", &p.DoubledPawnMg)
+	add("DoubledPawnEg", &p.DoubledPawnEg)
+	add("IsolatedPawnMg", &p.IsolatedPawnMg)
+	add("IsolatedPawnEg", &p.IsolatedPawnEg)
+	add("BackwardPawnMg", &p.BackwardPawnMg)
+	add("BackwardPawnEg", &p.BackwardPawnEg)
+	addRanks("ConnectedPawnMg", p.ConnectedPawnMg[:], 2, 7)
+	addRanks("ConnectedPawnEg", p.ConnectedPawnEg[:], 2, 7)
+	addRanks("PassedPawnMg", p.PassedPawnMg[:], 2, 7)
+	addRanks("PassedPawnEg", p.PassedPawnEg[:], 2, 7)
+	addRanks("PasserKingWeight", p.PasserKingWeight[:], 4, 7)
+
+	add("PawnShieldAdvancedMg", &p.PawnShieldAdvancedMg)
+	add("PawnShieldMissingMg", &p.PawnShieldMissingMg)
+	add("SemiOpenFileNearKingMg", &p.SemiOpenFileNearKingMg)
+	add("OpenFileNearKingMg", &p.OpenFileNearKingMg)
+	addPieceTypes("KingAttackWeights", p.KingAttackWeights[:])
+	addRanks("KingAttackerScales", p.KingAttackerScales[:], 2, 7)
+	addRanks("PawnStormMg", p.PawnStormMg[:], 1, 7)
+
+	addPieceTypes("MobilityMg", p.MobilityMg[:])
+	addPieceTypes("MobilityEg", p.MobilityEg[:])
+
+	add("BishopPairMg", &p.BishopPairMg)
+	add("BishopPairEg", &p.BishopPairEg)
+	add("RookOpenFileMg", &p.RookOpenFileMg)
+	add("RookOpenFileEg", &p.RookOpenFileEg)
+	add("RookSemiOpenFileMg", &p.RookSemiOpenFileMg)
+	add("RookSemiOpenFileEg", &p.RookSemiOpenFileEg)
+	add("RookOn7thMg", &p.RookOn7thMg)
+	add("RookOn7thEg", &p.RookOn7thEg)
+	add("KnightOutpostMg", &p.KnightOutpostMg)
+	add("KnightOutpostEg", &p.KnightOutpostEg)
+	add("BishopOutpostMg", &p.BishopOutpostMg)
+	add("BishopOutpostEg", &p.BishopOutpostEg)
+	return vector
+}
+
+func (p *EvalParams) Copy() *EvalParams {
+	paramsCopy := *p
+	return &paramsCopy
+}
+
+// MarshalJSON writes the params as a flat object of named weights, one per line and in
+// the order of the vector, so that parameter files diff cleanly
+func (p *EvalParams) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	vector := p.Vector()
+	for paramIdx, param := range vector {
+		name, _ := json.Marshal(param.Name)
+		buf.WriteString(fmt.Sprintf("  %s: %d", name, *param.Value))
+		if paramIdx < len(vector)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads weights written by MarshalJSON. Weights missing from the data are
+// left as they are, so that a file may hold only the weights it changes.
+func (p *EvalParams) UnmarshalJSON(data []byte) error {
+	var values map[string]int16
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	vector := p.Vector()
+	paramsByName := make(map[string]EvalParam, len(vector))
+	for _, param := range vector {
+		paramsByName[param.Name] = param
+	}
+	for name, value := range values {
+		param, exists := paramsByName[name]
+		if !exists {
+			return fmt.Errorf("unknown eval param: %s", name)
+		}
+		*param.Value = value
+	}
+	return nil
+}
+
+// LoadEvalParams reads a JSON file written by SaveEvalParams, with any weight missing from
+// the file left at its default
+func LoadEvalParams(path string) (*EvalParams, error) {
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+	params := DefaultEvalParams()
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, fmt.Errorf("could not parse eval params %s: %s", path, err)
+	}
+	return params, nil
+}
+
+// SaveEvalParams writes the params to a JSON file
+func SaveEvalParams(path string, params *EvalParams) error {
+	data, _ := params.MarshalJSON()
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// SetEvalParams swaps in the params read by the evaluation. No search may be running.
+func SetEvalParams(params *EvalParams) {
+	evalParams = params
+}
+
+func pieceTypeName(pt PieceType) string {
+	switch pt {
+	case PAWN:
+		return "pawn"
+	case KNIGHT:
+		return "knight"
+	case BISHOP:
+		return "bishop"
+	case ROOK:
+		return "rook"
+	case QUEEN:
+		return "queen"
+	case KING:
+		return "king"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EvalParams", func() {
+	Describe("#Vector", func() {
+		It("names every weight uniquely", func() {
+			names := make(map[string]bool)
+			for _, param := range DefaultEvalParams().Vector() {
+				Expect(names).ToNot(HaveKey(param.Name))
+				names[param.Name] = true
+			}
+			Expect(names).To(HaveKey("PassedPawnMg[4]"))
+			Expect(names).To(HaveKey("MobilityEg[knight]"))
+		})
+		It("points into the params", func() {
+			params := DefaultEvalParams()
+			for _, param := range params.Vector() {
+				if param.Name == "MobilityMg[rook]" {
+					*param.Value = 7
+				}
+			}
+			Expect(params.MobilityMg[ROOK]).To(Equal(int16(7)))
+		})
+	})
+	Describe("#Copy", func() {
+		It("doesn't share the tables", func() {
+			params := DefaultEvalParams()
+			paramsCopy := params.Copy()
+			paramsCopy.PassedPawnMg[5]++
+			Expect(params.PassedPawnMg[5]).To(Equal(DefaultEvalParams().PassedPawnMg[5]))
+		})
+	})
+	Describe("#MarshalJSON", func() {
+		It("writes every weight in the order of the vector", func() {
+			params := DefaultEvalParams()
+			data, marshalErr := params.MarshalJSON()
+			Expect(marshalErr).ToNot(HaveOccurred())
+			var decoded map[string]int16
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+
+			vector := params.Vector()
+			Expect(decoded).To(HaveLen(len(vector)))
+			lastIdx := -1
+			for _, param := range vector {
+				Expect(decoded[param.Name]).To(Equal(*param.Value))
+				idx := strings.Index(string(data), "\""+param.Name+"\"")
+				Expect(idx).To(BeNumerically(">", lastIdx))
+				lastIdx = idx
+			}
+		})
+	})
+	Describe("#LoadEvalParams", func() {
+		var dir string
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+		writeFile := func(contents string) string {
+			path := filepath.Join(dir, "params.json")
+			Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+			return path
+		}
+		It("reads back the params that were saved", func() {
+			params := DefaultEvalParams()
+			params.KnightVal = 310
+			params.DrawVal = 0
+			params.PawnStormMg[2] = -40
+			path := filepath.Join(dir, "params.json")
+			Expect(SaveEvalParams(path, params)).To(Succeed())
+			loaded, loadErr := LoadEvalParams(path)
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(*loaded).To(Equal(*params))
+		})
+		It("keeps the defaults of weights missing from the file", func() {
+			loaded, loadErr := LoadEvalParams(writeFile(`{"QueenVal": 900}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			expected := DefaultEvalParams()
+			expected.QueenVal = 900
+			Expect(*loaded).To(Equal(*expected))
+		})
+		It("rejects unknown or out of range weights", func() {
+			_, loadErr := LoadEvalParams(writeFile(`{"KingVal": 1000}`))
+			Expect(loadErr).To(HaveOccurred())
+			_, loadErr = LoadEvalParams(writeFile(`{"QueenVal": 40000}`))
+			Expect(loadErr).To(HaveOccurred())
+			_, loadErr = LoadEvalParams(filepath.Join(dir, "missing.json"))
+			Expect(loadErr).To(HaveOccurred())
+		})
+	})
+})
@@ -1,9 +1,13 @@
 package main_test
 
 import (
+	"encoding/json"
 	"github.com/CameronHonis/Mila"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 var _ = Describe("SortMoves", func() {
@@ -58,3 +62,155 @@ var _ = Describe("SortMoves", func() {
 		})
 	})
 })
+
+var _ = Describe("MateMoves", func() {
+	It("converts mate scores to moves until mate", func() {
+		Expect(main.MateMoves(main.MATE_VAL - 1)).To(Equal(1))
+		Expect(main.MateMoves(main.MATE_VAL - 3)).To(Equal(2))
+		Expect(main.MateMoves(main.MATE_VAL - 5)).To(Equal(3))
+	})
+	It("is negative when the side to move gets mated", func() {
+		Expect(main.MateMoves(-main.MATE_VAL + 2)).To(Equal(-1))
+		Expect(main.MateMoves(-main.MATE_VAL + 4)).To(Equal(-2))
+	})
+})
+
+var _ = Describe("IsMateScore", func() {
+	It("distinguishes mate scores from evaluations", func() {
+		Expect(main.IsMateScore(main.MATE_VAL - 7)).To(BeTrue())
+		Expect(main.IsMateScore(-main.MATE_VAL + 7)).To(BeTrue())
+		Expect(main.IsMateScore(main.QUEEN_VAL * 3)).To(BeFalse())
+	})
+})
+
+// mirrorFEN flips the board vertically and swaps the colors of the pieces, the side to
+// move and the castling rights
+func mirrorFEN(fen string) string {
+	fields := strings.Split(fen, " ")
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		castling := swapCase(fields[2])
+		fields[2] = ""
+		for _, right := range "KQkq" {
+			if strings.ContainsRune(castling, right) {
+				fields[2] += string(right)
+			}
+		}
+	}
+	if fields[3] != "-" {
+		fields[3] = string(fields[3][0]) + strconv.Itoa(9-int(fields[3][1]-'0'))
+	}
+	return strings.Join(fields, " ")
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+var _ = Describe("EvalPos", func() {
+	It("scores a mirrored position the same for the side to move", func() {
+		fens := []string{
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			"rnbqkb1r/pp1p1ppp/5n2/2pPp3/8/8/PPP1PPPP/RNBQKBNR w KQkq e6 0 4",
+			"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+			"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+			"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1",
+		}
+		for _, fen := range fens {
+			pos, posErr := main.FromFEN(fen)
+			Expect(posErr).ToNot(HaveOccurred())
+			mirrored, mirroredErr := main.FromFEN(mirrorFEN(fen))
+			Expect(mirroredErr).ToNot(HaveOccurred())
+			Expect(main.EvalPos(mirrored)).To(Equal(main.EvalPos(pos)), fen)
+		}
+	})
+	It("prefers developed pieces to pieces on the back rank", func() {
+		developed, _ := main.FromFEN("rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1")
+		Expect(main.EvalPos(developed)).To(BeNumerically("<", 0))
+	})
+})
+
+var _ = Describe("EvalBreakdown", func() {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r1b2rk1/ppp2ppp/2n5/3q4/3P4/5N2/PP3PPP/R2QKB1R w KQ - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 b - - 0 1",
+	}
+	It("totals to the evaluation from white's point of view", func() {
+		for _, fen := range fens {
+			pos, _ := main.FromFEN(fen)
+			eval := main.EvalPos(pos)
+			if strings.Split(fen, " ")[1] == "b" {
+				eval = -eval
+			}
+			Expect(main.NewEvalBreakdown(pos).Total()).To(Equal(eval), fen)
+		}
+	})
+	It("swaps the sides of a mirrored position", func() {
+		for _, fen := range fens {
+			pos, _ := main.FromFEN(fen)
+			mirrored, _ := main.FromFEN(mirrorFEN(fen))
+			breakdown, mirroredBreakdown := main.NewEvalBreakdown(pos), main.NewEvalBreakdown(mirrored)
+			for term := main.EvalTerm(0); term < main.N_EVAL_TERMS; term++ {
+				Expect(mirroredBreakdown.Terms[term][main.WHITE]).To(Equal(breakdown.Terms[term][main.BLACK]), fen)
+				Expect(mirroredBreakdown.Terms[term][main.BLACK]).To(Equal(breakdown.Terms[term][main.WHITE]), fen)
+			}
+		}
+	})
+	It("keeps the final evaluation, which is the draw score once the game is over", func() {
+		pos, _ := main.FromFEN("7k/8/8/8/8/8/6q1/6K1 w - - 0 1")
+		// taking the queen leaves bare kings, with black to move
+		_, _ = pos.MakeMove(main.NewNormalMove(main.SQ_G1, main.SQ_G2))
+		Expect(main.NewEvalBreakdown(pos).Eval).To(Equal(-main.DRAW_VAL))
+	})
+	It("encodes to JSON with the terms named and in order", func() {
+		pos, _ := main.FromFEN(fens[1])
+		breakdown := main.NewEvalBreakdown(pos)
+		out, err := json.Marshal(breakdown)
+		Expect(err).ToNot(HaveOccurred())
+		var decoded struct {
+			FEN   string
+			Phase int
+			Terms []struct {
+				Name  string
+				Total struct{ Mg, Eg int16 }
+			}
+			Total struct{ Mg, Eg int16 }
+			Eval  int16
+		}
+		Expect(json.Unmarshal(out, &decoded)).To(Succeed())
+		Expect(decoded.FEN).To(Equal(fens[1]))
+		Expect(decoded.Phase).To(Equal(breakdown.Phase))
+		Expect(decoded.Terms).To(HaveLen(int(main.N_EVAL_TERMS)))
+		var mgSum int16
+		for termIdx, term := range decoded.Terms {
+			Expect(term.Name).To(Equal(main.EvalTerm(termIdx).String()))
+			mgSum += term.Total.Mg
+		}
+		Expect(decoded.Total.Mg).To(Equal(mgSum))
+		Expect(decoded.Eval).To(Equal(breakdown.Total()))
+	})
+	It("lists every term in its table", func() {
+		pos, _ := main.FromFEN(fens[0])
+		table := main.NewEvalBreakdown(pos).String()
+		for term := main.EvalTerm(0); term < main.N_EVAL_TERMS; term++ {
+			Expect(table).To(ContainSubstring(term.String()))
+		}
+	})
+})
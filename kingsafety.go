@@ -0,0 +1,103 @@
+package main
+
+import "math/bits"
+
+const KING_SAFETY_ENABLED = true
+
+// MAX_KING_ATTACK_PENALTY bounds the king attack penalty, which otherwise grows without
+// limit with the number of attackers
+const MAX_KING_ATTACK_PENALTY = 1000
+
+// EvalKingSafety scores how exposed the color's king is, from the color's point of view.
+// Exposure only matters while there is enough material left to mount an attack, so the
+// terms are middlegame only and fade out as the game phase falls.
+func EvalKingSafety(pos *Position, color Color) TaperedScore {
+	kingSq := pos.pieceBitboards[NewPiece(KING, color)].FirstSq()
+	if kingSq == NULL_SQ {
+		return TaperedScore{}
+	}
+	return TaperedScore{mg: kingAttackPenalty(pos, kingSq, color) + kingShelterPenalty(pos, kingSq, color)}
+}
+
+// KingZoneBB is the squares around the king, and the squares one rank further in
+// front of it, where enemy attacks are most dangerous
+func KingZoneBB(kingSq Square, color Color) Bitboard {
+	zone := KingAttacksBB(kingSq) | BBWithSquares(kingSq)
+	if color == WHITE {
+		return zone | zone<<8
+	}
+	return zone | zone>>8
+}
+
+// kingAttackPenalty weighs the enemy pieces attacking the king zone, scaled up by how
+// many of them take part in the attack
+func kingAttackPenalty(pos *Position, kingSq Square, color Color) int16 {
+	zone := KingZoneBB(kingSq, color)
+	occupied := pos.OccupiedBB()
+	var nAttackers int
+	var weight int
+	for _, pt := range []PieceType{KNIGHT, BISHOP, ROOK, QUEEN} {
+		for attackersLeft := pos.pieceBitboards[NewPiece(pt, color.Opp())]; attackersLeft != 0; {
+			var sq Square
+			sq, attackersLeft = attackersLeft.PopFirstSq()
+			var attacksBB Bitboard
+			if pt == KNIGHT {
+				attacksBB = KnightAttacksBB(sq)
+			} else {
+				attacksBB = SlidingAttacksBB(occupied, sq, pt)
+			}
+			if zoneAttacksBB := attacksBB & zone; zoneAttacksBB != 0 {
+				nAttackers++
+				weight += int(evalParams.KingAttackWeights[pt]) * bits.OnesCount64(uint64(zoneAttacksBB))
+			}
+		}
+	}
+	scales := &evalParams.KingAttackerScales
+	penalty := weight * int(scales[MinInt(nAttackers, len(scales)-1)]) / 100
+	return -int16(MaxInt(-MAX_KING_ATTACK_PENALTY, MinInt(penalty, MAX_KING_ATTACK_PENALTY)))
+}
+
+// kingShelterPenalty looks at the king's file and the files next to it, for missing or
+// advanced shield pawns, enemy pawns storming the king and open lines towards it
+func kingShelterPenalty(pos *Position, kingSq Square, color Color) int16 {
+	ownPawns := pos.pieceBitboards[NewPiece(PAWN, color)]
+	enemyPawns := pos.pieceBitboards[NewPiece(PAWN, color.Opp())]
+	kingFile := int(kingSq.File())
+	var mg int16
+	for file := MaxInt(1, kingFile-1); file <= MinInt(N_FILES, kingFile+1); file++ {
+		fileBB := BBWithFile(uint8(file), 0b11111111)
+		if fileBB&ownPawns == 0 {
+			if fileBB&enemyPawns == 0 {
+				mg += evalParams.OpenFileNearKingMg
+			} else {
+				mg += evalParams.SemiOpenFileNearKingMg
+			}
+		}
+
+		aheadBB := forwardFileBBs[color][SqFromCoords(int(kingSq.Rank()), file)]
+		if shieldSq := closestSq(aheadBB&ownPawns, color); shieldSq == NULL_SQ {
+			mg += evalParams.PawnShieldMissingMg
+		} else if ranksAhead(kingSq, shieldSq, color) == 2 {
+			mg += evalParams.PawnShieldAdvancedMg
+		} else if ranksAhead(kingSq, shieldSq, color) > 2 {
+			mg += evalParams.PawnShieldMissingMg
+		}
+		if stormSq := closestSq(aheadBB&enemyPawns, color); stormSq != NULL_SQ {
+			mg += evalParams.PawnStormMg[ranksAhead(kingSq, stormSq, color)]
+		}
+	}
+	return mg
+}
+
+// closestSq returns the square of the bitboard that is furthest back from the color's
+// point of view
+func closestSq(bb Bitboard, color Color) Square {
+	if color == WHITE {
+		return bb.FirstSq()
+	}
+	return bb.LastSq()
+}
+
+func ranksAhead(fromSq, sq Square, color Color) int {
+	return int(sq.RelativeRank(color)) - int(fromSq.RelativeRank(color))
+}
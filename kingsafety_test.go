@@ -0,0 +1,70 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func kingSafetyFromFEN(fen string, color Color) TaperedScore {
+	pos, posErr := FromFEN(fen)
+	Expect(posErr).ToNot(HaveOccurred())
+	return EvalKingSafety(pos, color)
+}
+
+var _ = Describe("King safety", func() {
+	It("does not penalize a castled king behind its pawns", func() {
+		Expect(kingSafetyFromFEN("6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1", WHITE)).To(Equal(TaperedScore{}))
+	})
+	It("only scores the middlegame", func() {
+		score := kingSafetyFromFEN("6k1/8/8/8/8/8/8/6K1 w - - 0 1", WHITE)
+		Expect(score.mg).To(BeNumerically("<", 0))
+		Expect(score.eg).To(BeZero())
+	})
+	It("penalizes missing and advanced shield pawns", func() {
+		advanced := kingSafetyFromFEN("6k1/5ppp/8/8/8/6P1/5P1P/6K1 w - - 0 1", WHITE)
+		Expect(advanced.mg).To(Equal(evalParams.PawnShieldAdvancedMg))
+		missing := kingSafetyFromFEN("6k1/5ppp/8/8/6P1/8/5P1P/6K1 w - - 0 1", WHITE)
+		Expect(missing.mg).To(Equal(evalParams.PawnShieldMissingMg))
+	})
+	It("penalizes open and semi-open files near the king", func() {
+		semiOpen := kingSafetyFromFEN("6k1/5ppp/8/8/8/8/5P1P/6K1 w - - 0 1", WHITE)
+		Expect(semiOpen.mg).To(Equal(evalParams.SemiOpenFileNearKingMg + evalParams.PawnShieldMissingMg))
+		open := kingSafetyFromFEN("6k1/5p1p/8/8/8/8/5P1P/6K1 w - - 0 1", WHITE)
+		Expect(open.mg).To(Equal(evalParams.OpenFileNearKingMg + evalParams.PawnShieldMissingMg))
+	})
+	It("penalizes enemy pawns storming the king", func() {
+		storm := kingSafetyFromFEN("6k1/5p1p/8/8/8/6p1/5PPP/6K1 w - - 0 1", WHITE)
+		Expect(storm.mg).To(Equal(evalParams.PawnStormMg[2]))
+	})
+	It("bounds the penalty of a crowd of attackers", func() {
+		crowded := "6k1/8/8/8/8/1qqqqqqq/5PPP/q5K1 w - - 0 1"
+		Expect(kingSafetyFromFEN(crowded, WHITE).mg).To(BeNumerically("<", 0))
+
+		// tuned weights can take the attack weight far past what fits in an int16
+		params := DefaultEvalParams()
+		params.KingAttackWeights[QUEEN] = 1000
+		DeferCleanup(SetEvalParams, evalParams)
+		SetEvalParams(params)
+		pos, posErr := FromFEN(crowded)
+		Expect(posErr).ToNot(HaveOccurred())
+		Expect(kingAttackPenalty(pos, SQ_G1, WHITE)).To(Equal(int16(-MAX_KING_ATTACK_PENALTY)))
+	})
+	It("ignores a lone attacker, but not several", func() {
+		lone := kingSafetyFromFEN("6k1/8/8/8/8/7q/5PPP/6K1 w - - 0 1", WHITE)
+		Expect(lone.mg).To(BeZero())
+		several := kingSafetyFromFEN("6k1/8/8/8/8/5n1q/5PPP/6K1 w - - 0 1", WHITE)
+		Expect(several.mg).To(BeNumerically("<", 0))
+	})
+	It("scores black's king from black's side of the board", func() {
+		Expect(kingSafetyFromFEN("6k1/5p1p/6p1/8/8/8/5PPP/6K1 w - - 0 1", BLACK)).
+			To(Equal(kingSafetyFromFEN("6k1/5ppp/8/8/8/6P1/5P1P/6K1 w - - 0 1", WHITE)))
+	})
+	Describe("#KingZoneBB", func() {
+		It("covers the squares around the king and a rank further ahead", func() {
+			Expect(KingZoneBB(SQ_G1, WHITE)).To(Equal(BBWithSquares(SQ_F1, SQ_G1, SQ_H1, SQ_F2, SQ_G2, SQ_H2,
+				SQ_F3, SQ_G3, SQ_H3)))
+			Expect(KingZoneBB(SQ_G8, BLACK)).To(Equal(BBWithSquares(SQ_F8, SQ_G8, SQ_H8, SQ_F7, SQ_G7, SQ_H7,
+				SQ_F6, SQ_G6, SQ_H6)))
+		})
+	})
+})
@@ -0,0 +1,88 @@
+package main
+
+import "math/bits"
+
+// Legality tells whether pseudo-legal moves leave the mover's king safe, without making
+// them. The pieces checking the king and the pieces pinned to it are found once per
+// position, after which most moves are checked with a couple of bitboard lookups.
+type Legality struct {
+	pos      *Position
+	enemy    Color
+	kingSq   Square
+	checkers Bitboard
+	pinned   Bitboard
+	// evasionsBB holds the squares a non-king move must end on, those that capture or
+	// block the checker if in check, and every square otherwise
+	evasionsBB Bitboard
+}
+
+func NewLegality(pos *Position) *Legality {
+	color := NewColor(pos.isWhiteTurn)
+	kingSq := pos.pieceBitboards[NewPiece(KING, color)].FirstSq()
+	occupiedBB := pos.OccupiedBB()
+	enemyBB := pos.colorBitboards[color.Opp()]
+	l := &Legality{
+		pos:        pos,
+		enemy:      color.Opp(),
+		kingSq:     kingSq,
+		checkers:   pos.attackersBB(kingSq, occupiedBB) & enemyBB,
+		evasionsBB: ^Bitboard(0),
+	}
+	if l.checkers != 0 {
+		l.evasionsBB = BetweenBB(kingSq, l.checkers.FirstSq()) | l.checkers
+	}
+
+	// a piece is pinned if it's the only piece between the king and an enemy slider
+	diagSliders := pos.pieceBitboards[NewPiece(BISHOP, color.Opp())] | pos.pieceBitboards[NewPiece(QUEEN, color.Opp())]
+	straightSliders := pos.pieceBitboards[NewPiece(ROOK, color.Opp())] | pos.pieceBitboards[NewPiece(QUEEN, color.Opp())]
+	kingBB := BBWithSquares(kingSq)
+	snipers := SlidingAttacksBB(kingBB, kingSq, BISHOP)&diagSliders |
+		SlidingAttacksBB(kingBB, kingSq, ROOK)&straightSliders
+	for snipers > 0 {
+		var sniperSq Square
+		sniperSq, snipers = snipers.PopFirstSq()
+		blockers := BetweenBB(kingSq, sniperSq) & occupiedBB
+		if bits.OnesCount64(uint64(blockers)) == 1 {
+			l.pinned |= blockers & pos.colorBitboards[color]
+		}
+	}
+	return l
+}
+
+func (l *Legality) IsChecked() bool {
+	return l.checkers != 0
+}
+
+func (l *Legality) IsDoubleChecked() bool {
+	return bits.OnesCount64(uint64(l.checkers)) > 1
+}
+
+// IsLegal expects a pseudo-legal move for the position the Legality was built from
+func (l *Legality) IsLegal(pMove Move) bool {
+	start := pMove.StartSq()
+	end := pMove.EndSq()
+	enemyBB := l.pos.colorBitboards[l.enemy]
+	if start == l.kingSq {
+		if pMove.Type() == CASTLING {
+			return l.checkers == 0 &&
+				!l.pos.isSquareAttacked(l.enemy, (start+end)/2) &&
+				!l.pos.isSquareAttacked(l.enemy, end)
+		}
+		// the king is taken off the board, it can't hide from a slider behind itself
+		occupiedBB := l.pos.OccupiedBB() &^ BBWithSquares(start)
+		return l.pos.attackersBB(end, occupiedBB)&enemyBB == 0
+	}
+	if l.IsDoubleChecked() {
+		return false
+	}
+	if pMove.Type() == CAPTURES_EN_PASSANT {
+		// both pawns leave the rank at once, which may uncover a slider on the king
+		capturedBB := BBWithSquares(SqFromCoords(int(start.Rank()), int(end.File())))
+		occupiedBB := l.pos.OccupiedBB()&^(BBWithSquares(start)|capturedBB) | BBWithSquares(end)
+		return l.pos.attackersBB(l.kingSq, occupiedBB)&enemyBB&^capturedBB == 0
+	}
+	if l.evasionsBB&BBWithSquares(end) == 0 {
+		return false
+	}
+	return l.pinned&BBWithSquares(start) == 0 || LineBB(l.kingSq, start)&BBWithSquares(end) != 0
+}
@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	"github.com/CameronHonis/Mila"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Legality", func() {
+	legalMoves := func(fen string) []main.Move {
+		pos, posErr := main.FromFEN(fen)
+		Expect(posErr).ToNot(HaveOccurred())
+		return main.GenLegalMoves(pos)
+	}
+	When("a piece is pinned to the king", func() {
+		It("can only move along the pin", func() {
+			moves := legalMoves("4k3/4r3/8/8/8/8/4R3/4K3 w - - 0 1")
+			Expect(moves).To(ContainElements(main.NewNormalMove(main.SQ_E2, main.SQ_E5), main.NewNormalMove(main.SQ_E2, main.SQ_E7)))
+			Expect(moves).ToNot(ContainElement(main.NewNormalMove(main.SQ_E2, main.SQ_D2)))
+		})
+	})
+	When("the king is in check", func() {
+		It("can capture or block the checker", func() {
+			moves := legalMoves("4k3/8/8/8/8/1N6/8/r3K3 w - - 0 1")
+			Expect(moves).To(ContainElements(main.NewNormalMove(main.SQ_B3, main.SQ_A1), main.NewNormalMove(main.SQ_B3, main.SQ_C1)))
+			Expect(moves).ToNot(ContainElement(main.NewNormalMove(main.SQ_B3, main.SQ_D2)))
+		})
+		It("can't step back along the checking slider's line", func() {
+			moves := legalMoves("4r2k/8/8/8/4K3/8/8/8 w - - 0 1")
+			Expect(moves).ToNot(ContainElement(main.NewNormalMove(main.SQ_E4, main.SQ_E3)))
+			Expect(moves).To(ContainElement(main.NewNormalMove(main.SQ_E4, main.SQ_D3)))
+		})
+		It("can't castle", func() {
+			moves := legalMoves("4k3/8/8/8/8/8/4r3/R3K2R w KQ - 0 1")
+			for _, move := range moves {
+				Expect(move.Type()).ToNot(Equal(main.CASTLING))
+			}
+		})
+	})
+	When("the king is in double check", func() {
+		It("can only move the king", func() {
+			// the knight could capture the rook, but the bishop would still give check
+			moves := legalMoves("4k3/8/8/8/1b6/8/2N5/r3K3 w - - 0 1")
+			Expect(moves).To(ConsistOf(main.NewNormalMove(main.SQ_E1, main.SQ_E2), main.NewNormalMove(main.SQ_E1, main.SQ_F2)))
+		})
+	})
+	It("doesn't castle through an attacked square", func() {
+		moves := legalMoves("4k3/8/8/8/8/8/5r2/R3K2R w KQ - 0 1")
+		Expect(moves).ToNot(ContainElement(main.NewMove(main.SQ_E1, main.SQ_G1, main.NULL_SQ, main.EMPTY_PIECE_TYPE, true)))
+		Expect(moves).To(ContainElement(main.NewMove(main.SQ_E1, main.SQ_C1, main.NULL_SQ, main.EMPTY_PIECE_TYPE, true)))
+	})
+	When("capturing en passant takes both pawns off the king's rank", func() {
+		It("is illegal if it uncovers a slider on the king", func() {
+			moves := legalMoves("8/8/8/K2pP2r/8/8/8/4k3 w - d6 0 2")
+			Expect(moves).ToNot(ContainElement(main.NewEnPassantMove(main.SQ_E5, main.SQ_D6)))
+			moves = legalMoves("8/8/8/K2pP3/8/8/8/4k3 w - d6 0 2")
+			Expect(moves).To(ContainElement(main.NewEnPassantMove(main.SQ_E5, main.SQ_D6)))
+		})
+	})
+	It("captures the checking pawn en passant", func() {
+		moves := legalMoves("8/8/8/5k2/3pP3/8/8/4K3 b - e3 0 1")
+		Expect(moves).To(ContainElement(main.NewEnPassantMove(main.SQ_D4, main.SQ_E3)))
+	})
+})
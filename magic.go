@@ -0,0 +1,144 @@
+package main
+
+import (
+	"log"
+	"math/bits"
+)
+
+// Magic maps the occupancy of the squares that can block a slider on some square to an
+// index into that square's attack table. Multiplying the relevant occupancy by the magic
+// number gathers the occupied squares into the top bits of the product, which are unique
+// for every occupancy that leads to different attacks.
+type Magic struct {
+	mask    Bitboard
+	magic   uint64
+	shift   uint8
+	attacks []Bitboard
+}
+
+func (m *Magic) AttacksBB(occupied Bitboard) Bitboard {
+	return m.attacks[(uint64(occupied&m.mask)*m.magic)>>m.shift]
+}
+
+var rookMagics [N_SQUARES]Magic
+var bishopMagics [N_SQUARES]Magic
+
+var rookDirs = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+var bishopDirs = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// The magic numbers were found by trying sparse random numbers until each one indexed
+// its square's attacks without collisions
+var rookMagicNumbers = [N_SQUARES]uint64{
+	0x2180001240002880, 0x13c00240a0021004, 0x4900100900402000, 0x0480080010000580,
+	0x0200081020020004, 0x0180020001800400, 0x4080008001000200, 0x020002c28200240b,
+	0x0a21800020914008, 0x0000404000201000, 0x0901001040200100, 0x0002808008001000,
+	0x0008800400080081, 0x0000800400020080, 0x800c000201102408, 0x00438001c8800100,
+	0x8601020020420080, 0x0440002008003000, 0x4420008010008024, 0x0404250009001000,
+	0x0002020020041008, 0x0002008004008002, 0x0024040002104108, 0x0a40020000811054,
+	0x410081248004c000, 0x0840008080402000, 0x4580104100200100, 0x0001000900100022,
+	0xc00c040080800800, 0x2402001200049058, 0x5800014400081042, 0x1004c84200110884,
+	0x0120004000808000, 0x0080401000402000, 0x2020801001802000, 0x0004840800801000,
+	0xb100040080800800, 0xa500800400800200, 0x8020084104005002, 0x0040908242000c27,
+	0x0914208440008003, 0x0000200050004000, 0x2020021000828020, 0x1008020100101000,
+	0x1040080004008080, 0x000a000400808002, 0x0180100221040048, 0x0c62208044020001,
+	0x0c00210080004100, 0x0000882000400880, 0x20a0008010002080, 0x8020080010008080,
+	0x2800040008008080, 0x0000040080020080, 0x0800825035080400, 0x0100410080442200,
+	0x00078000b0410821, 0x0140002011008041, 0x3000081020004101, 0x0900209001000489,
+	0x0002000810042002, 0x1001008400080241, 0x5010102120a20804, 0x0c01000882002041,
+}
+
+var bishopMagicNumbers = [N_SQUARES]uint64{
+	0x0020128402540040, 0x0020080a00a42102, 0x1204083081024000, 0x4008204040442100,
+	0x2062021002085020, 0x0405102610850402, 0x042080841040c000, 0x800042004108410a,
+	0x9200101c050c4408, 0x0000051806004206, 0x0000083a00420222, 0x0000020a02000040,
+	0x0020141028404500, 0x0084042208400202, 0x00802080a8201000, 0x200a020200a40401,
+	0x264000c952048400, 0x2444500284481200, 0x8008001402212200, 0x4018000104110000,
+	0x0004000880e00002, 0x2400202200904801, 0x8005000424110404, 0x00284241210808aa,
+	0x0a100a0040688d14, 0x8308280402e20800, 0x0101010030040220, 0x8091080204004011,
+	0x0611010000104000, 0x0a50108109008080, 0x0201042000440400, 0x8000404040940408,
+	0x1008121208400490, 0x04440a20090827a0, 0x48020124050a0808, 0x000e004040040100,
+	0x1050020081061004, 0x00020c0302007000, 0x100a042400104600, 0x00020c110000208a,
+	0x2002084440014410, 0x2404809009181030, 0x0080208020801000, 0x00202a0214000200,
+	0x0000220602000410, 0x8022021042014101, 0x006002040100904a, 0x00120204010a9822,
+	0x7040420820091088, 0x8400804808043201, 0x2062082484101028, 0x0100421420880010,
+	0x8808000843040010, 0xc8602104040822a4, 0x0010100208004620, 0x2004100c03548000,
+	0x8002048048080408, 0x57080024020210c0, 0x1041000444240400, 0x2a40000001841420,
+	0x2020001041084840, 0x2081820405080200, 0x881220200c088284, 0x0440010102248900,
+}
+
+func initMagics() {
+	for sq := SQ_A1; sq < N_SQUARES; sq++ {
+		rookMagics[sq] = newMagic(sq, rookDirs, rookMagicNumbers[sq])
+		bishopMagics[sq] = newMagic(sq, bishopDirs, bishopMagicNumbers[sq])
+	}
+}
+
+// newMagic fills the square's attack table for every occupancy of the relevant squares.
+// Two occupancies may share an index only if they lead to the same attacks.
+func newMagic(sq Square, dirs [4][2]int, magic uint64) Magic {
+	mask := relevantOccupancyMask(sq, dirs)
+	nBits := bits.OnesCount64(uint64(mask))
+	m := Magic{
+		mask:    mask,
+		magic:   magic,
+		shift:   uint8(64 - nBits),
+		attacks: make([]Bitboard, 1<<nBits),
+	}
+	isUsed := make([]bool, 1<<nBits)
+	// enumerate every subset of the mask (the Carry-Rippler trick)
+	occupied := Bitboard(0)
+	for {
+		idx := (uint64(occupied) * magic) >> m.shift
+		attacksBB := walkSlidingAttacksBB(occupied, sq, dirs)
+		if isUsed[idx] && m.attacks[idx] != attacksBB {
+			log.Fatalf("magic %#x collides on square %s", magic, sq)
+		}
+		isUsed[idx] = true
+		m.attacks[idx] = attacksBB
+		occupied = (occupied - mask) & mask
+		if occupied == 0 {
+			break
+		}
+	}
+	return m
+}
+
+// relevantOccupancyMask returns the squares whose occupancy affects the slider's attacks.
+// The last square in each direction is attacked whether it's occupied or not.
+func relevantOccupancyMask(sq Square, dirs [4][2]int) Bitboard {
+	var mask Bitboard
+	for _, dir := range dirs {
+		rank := int(sq.Rank()) + dir[0]
+		file := int(sq.File()) + dir[1]
+		for isOnBoard(rank+dir[0], file+dir[1]) {
+			mask |= BBWithSquares(SqFromCoords(rank, file))
+			rank += dir[0]
+			file += dir[1]
+		}
+	}
+	return mask
+}
+
+// walkSlidingAttacksBB finds the slider's attacks by walking each direction until the
+// edge of the board or an occupied square, which is slow but obviously correct
+func walkSlidingAttacksBB(occupied Bitboard, sq Square, dirs [4][2]int) Bitboard {
+	var attacksBB Bitboard
+	for _, dir := range dirs {
+		rank := int(sq.Rank()) + dir[0]
+		file := int(sq.File()) + dir[1]
+		for isOnBoard(rank, file) {
+			sqBB := BBWithSquares(SqFromCoords(rank, file))
+			attacksBB |= sqBB
+			if occupied&sqBB != 0 {
+				break
+			}
+			rank += dir[0]
+			file += dir[1]
+		}
+	}
+	return attacksBB
+}
+
+func isOnBoard(rank, file int) bool {
+	return rank >= 1 && rank <= 8 && file >= 1 && file <= 8
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Magic", func() {
+	It("looks up the same attacks as walking the board, on every square", func() {
+		rng := rand.New(rand.NewSource(1))
+		for sq := SQ_A1; sq < N_SQUARES; sq++ {
+			for i := 0; i < 200; i++ {
+				// sparse and dense boards both come up
+				occupied := Bitboard(rng.Uint64() & rng.Uint64())
+				if i%2 == 1 {
+					occupied = Bitboard(rng.Uint64() | rng.Uint64())
+				}
+				Expect(SlidingAttacksBB(occupied, sq, ROOK)).To(Equal(walkSlidingAttacksBB(occupied, sq, rookDirs)))
+				Expect(SlidingAttacksBB(occupied, sq, BISHOP)).To(Equal(walkSlidingAttacksBB(occupied, sq, bishopDirs)))
+			}
+		}
+	})
+	It("ignores occupancy that can't block the slider", func() {
+		// the rook sees the edge squares whether or not they're occupied
+		Expect(SlidingAttacksBB(BBWithSquares(SQ_A1, SQ_H8, SQ_D8), SQ_D4, ROOK)).
+			To(Equal(SlidingAttacksBB(0, SQ_D4, ROOK)))
+	})
+})
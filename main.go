@@ -11,7 +11,18 @@ const DEBUG = false
 const PROFILE = false
 
 func main() {
-	initAttackPrecomputes()
+	args, flagsErr := parseMainFlags(os.Args[1:])
+	if flagsErr != nil {
+		fmt.Println(flagsErr)
+		os.Exit(1)
+	}
+	if len(args) > 0 {
+		if err := runCli(args); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	if PROFILE {
 		f, _ := os.Create("cpu.prof")
@@ -19,10 +30,13 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 	fmt.Println("Mila v0.4.2 - a lightweight chess AI written in go by Cameron Honis")
-	tt := NewTranspTable()
+	tt := NewTranspTable(DEFAULT_HASH_MB)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
-	go NewUci(tt).Start()
+	go func() {
+		NewUci(tt).Start()
+		wg.Done()
+	}()
 
 	wg.Wait()
 }
@@ -355,6 +355,31 @@ func (m *Material) IsForcedDraw() bool {
 	return true
 }
 
+// HasPieces returns true if the color has material other than pawns. Sides with only
+// pawns are the most prone to zugzwang.
+func (m *Material) HasPieces(color Color) bool {
+	if color == WHITE {
+		return m[1]+m[2]+m[3]+m[4]+m[5] > 0
+	}
+	return m[7]+m[8]+m[9]+m[10]+m[11] > 0
+}
+
+// HasBishopPair returns true if the color has bishops on both light and dark squares
+func (m *Material) HasBishopPair(color Color) bool {
+	if color == WHITE {
+		return m.nWLightBishops() > 0 && m.nWDarkBishops() > 0
+	}
+	return m.nBLightBishops() > 0 && m.nBDarkBishops() > 0
+}
+
+// Phase returns how much of the non-pawn material is left, from MAX_PHASE with all of
+// it on the board down to 0 with none. Promotions can't push the phase past MAX_PHASE.
+func (m *Material) Phase() int {
+	nMinors := int(m[1]) + int(m[2]) + int(m[3]) + int(m[7]) + int(m[8]) + int(m[9])
+	phase := nMinors + 2*int(m.nRooks()) + 4*int(m.nQueens())
+	return MinInt(phase, MAX_PHASE)
+}
+
 func (m *Material) nQueens() uint8 {
 	return m[5] + m[11]
 }
@@ -0,0 +1,81 @@
+package main
+
+const KILLERS_ENABLED = true
+const HISTORY_ENABLED = true
+const COUNTER_MOVES_ENABLED = true
+const SEE_ORDERING_ENABLED = true
+
+// MAX_SEARCH_PLY bounds how far from the root the per-ply tables reach. The depth is a
+// uint8 and every ply from the root spends at least one ply of depth, so no node in the
+// main search is further from the root than this.
+const MAX_SEARCH_PLY = 256
+
+// N_KILLERS is the number of killer slots kept per ply
+const N_KILLERS = 2
+
+// MAX_HISTORY bounds the history scores. Each update pulls a score towards the bound by
+// a fraction of the distance left, so scores saturate instead of overflowing.
+const MAX_HISTORY = 16384
+
+// MoveOrderTables remembers which quiet moves caused beta cutoffs, so that they are
+// tried early in similar positions. Killers are remembered per ply from the root,
+// history per color and start and end square, and countermoves per previous move.
+type MoveOrderTables struct {
+	killers      [MAX_SEARCH_PLY][N_KILLERS]Move
+	history      [N_COLORS][N_SQUARES][N_SQUARES]int32
+	counterMoves [N_PIECES][N_SQUARES]Move
+}
+
+func (mot *MoveOrderTables) IsKiller(ply Ply, move Move) bool {
+	if ply >= MAX_SEARCH_PLY {
+		return false
+	}
+	for _, killer := range mot.killers[ply] {
+		if killer == move {
+			return true
+		}
+	}
+	return false
+}
+
+func (mot *MoveOrderTables) History(color Color, move Move) int32 {
+	return mot.history[color][move.StartSq()][move.EndSq()]
+}
+
+// CounterMove returns the quiet move that last refuted prevMove. The position must be the
+// one right after prevMove was made.
+func (mot *MoveOrderTables) CounterMove(pos *Position, prevMove Move) Move {
+	if prevMove == NULL_MOVE {
+		return NULL_MOVE
+	}
+	return mot.counterMoves[pos.pieces[prevMove.EndSq()]][prevMove.EndSq()]
+}
+
+// RecordCutoff rewards the quiet move that caused a beta cutoff, and penalizes the history
+// of the quiet moves that were searched before it without causing one.
+func (mot *MoveOrderTables) RecordCutoff(pos *Position, ply Ply, depth uint8, prevMove, move Move, failedQuiets []Move) {
+	if KILLERS_ENABLED && ply < MAX_SEARCH_PLY && mot.killers[ply][0] != move {
+		copy(mot.killers[ply][1:], mot.killers[ply][:N_KILLERS-1])
+		mot.killers[ply][0] = move
+	}
+	if HISTORY_ENABLED {
+		color := NewColor(pos.isWhiteTurn)
+		bonus := int32(MinInt(int(depth)*int(depth), MAX_HISTORY))
+		mot.updateHistory(color, move, bonus)
+		for _, failedQuiet := range failedQuiets {
+			mot.updateHistory(color, failedQuiet, -bonus)
+		}
+	}
+	if COUNTER_MOVES_ENABLED && prevMove != NULL_MOVE {
+		mot.counterMoves[pos.pieces[prevMove.EndSq()]][prevMove.EndSq()] = move
+	}
+}
+
+func (mot *MoveOrderTables) updateHistory(color Color, move Move, bonus int32) {
+	entry := &mot.history[color][move.StartSq()][move.EndSq()]
+	absBonus := bonus
+	if absBonus < 0 {
+		absBonus = -absBonus
+	}
+	*entry += bonus - *entry*absBonus/MAX_HISTORY
+}
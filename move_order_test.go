@@ -0,0 +1,62 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MoveOrderTables", func() {
+	var mot *MoveOrderTables
+	var pos *Position
+	BeforeEach(func() {
+		mot = &MoveOrderTables{}
+		var posErr error
+		pos, posErr = FromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		Expect(posErr).ToNot(HaveOccurred())
+	})
+	Describe("::RecordCutoff", func() {
+		It("keeps the most recent killers for the ply", func() {
+			moveA := NewNormalMove(SQ_B1, SQ_C3)
+			moveB := NewNormalMove(SQ_D2, SQ_D3)
+			moveC := NewNormalMove(SQ_F1, SQ_C4)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveA, nil)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveB, nil)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveC, nil)
+			Expect(mot.IsKiller(3, moveC)).To(BeTrue())
+			Expect(mot.IsKiller(3, moveB)).To(BeTrue())
+			Expect(mot.IsKiller(3, moveA)).To(BeFalse())
+			Expect(mot.IsKiller(4, moveC)).To(BeFalse())
+		})
+		It("does not fill both killer slots with the same move", func() {
+			moveA := NewNormalMove(SQ_B1, SQ_C3)
+			moveB := NewNormalMove(SQ_D2, SQ_D3)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveA, nil)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveB, nil)
+			mot.RecordCutoff(pos, 3, 1, NULL_MOVE, moveB, nil)
+			Expect(mot.IsKiller(3, moveA)).To(BeTrue())
+		})
+		It("rewards the cutoff move's history and penalizes the moves tried before it", func() {
+			move := NewNormalMove(SQ_B1, SQ_C3)
+			failedMove := NewNormalMove(SQ_D2, SQ_D3)
+			mot.RecordCutoff(pos, 3, 4, NULL_MOVE, move, []Move{failedMove})
+			Expect(mot.History(WHITE, move)).To(BeNumerically(">", 0))
+			Expect(mot.History(WHITE, failedMove)).To(BeNumerically("<", 0))
+			Expect(mot.History(BLACK, move)).To(Equal(int32(0)))
+		})
+		It("saturates the history instead of overflowing", func() {
+			move := NewNormalMove(SQ_B1, SQ_C3)
+			for i := 0; i < 10_000; i++ {
+				mot.RecordCutoff(pos, 3, 200, NULL_MOVE, move, nil)
+			}
+			Expect(mot.History(WHITE, move)).To(BeNumerically("<=", MAX_HISTORY))
+			Expect(mot.History(WHITE, move)).To(BeNumerically(">", MAX_HISTORY/2))
+		})
+		It("remembers the move as the countermove to the previous move", func() {
+			prevMove := NewNormalMove(SQ_B8, SQ_C6)
+			move := NewNormalMove(SQ_F1, SQ_B5)
+			mot.RecordCutoff(pos, 3, 1, prevMove, move, nil)
+			Expect(mot.CounterMove(pos, prevMove)).To(Equal(move))
+			Expect(mot.CounterMove(pos, NewNormalMove(SQ_G8, SQ_F6))).To(Equal(NULL_MOVE))
+		})
+	})
+})
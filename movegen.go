@@ -5,30 +5,43 @@ import (
 )
 
 type LegalMoveIter struct {
-	pos    *Position
-	pMoves []Move
-	idx    int
+	moves []Move
+	idx   int
 }
 
 func NewLegalMoveIter(pos *Position) *LegalMoveIter {
-	pMoves := GenPseudoLegalMoves(pos)
 	return &LegalMoveIter{
-		pos:    pos,
-		pMoves: pMoves,
-		idx:    0,
+		moves: GenLegalMoves(pos),
+		idx:   0,
 	}
 }
 
 func (iter *LegalMoveIter) Next() (move Move, done bool) {
-	for iter.idx < len(iter.pMoves) {
-		pMove := iter.pMoves[iter.idx]
-		iter.idx++
+	if iter.idx >= len(iter.moves) {
+		return NULL_MOVE, true
+	}
+	move = iter.moves[iter.idx]
+	iter.idx++
+	return move, false
+}
 
-		if iter.pos.IsLegalMove(pMove) {
-			return pMove, false
+// GenLegalMoves generates the moves that don't leave the king in check. Only the king can
+// get out of a double check, so only its moves are generated then.
+func GenLegalMoves(pos *Position) []Move {
+	legality := NewLegality(pos)
+	var pMoves []Move
+	if legality.IsDoubleChecked() {
+		pMoves = GenPseudoLegalKingMoves(pos, legality.kingSq)
+	} else {
+		pMoves = GenPseudoLegalMoves(pos)
+	}
+	moves := pMoves[:0]
+	for _, pMove := range pMoves {
+		if legality.IsLegal(pMove) {
+			moves = append(moves, pMove)
 		}
 	}
-	return NULL_MOVE, true
+	return moves
 }
 
 func GenPseudoLegalMoves(pos *Position) []Move {
@@ -53,6 +66,63 @@ func GenPseudoLegalMoves(pos *Position) []Move {
 	return rtn
 }
 
+// GenPseudoLegalCaptures generates the pseudo-legal captures and promotions, which are
+// the moves searched by quiescence and tried before quiet moves
+func GenPseudoLegalCaptures(pos *Position) []Move {
+	color := NewColor(pos.isWhiteTurn)
+	return genPseudoLegalMovesTo(pos, pos.colorBitboards[color.Opp()], true)
+}
+
+// GenPseudoLegalQuiets generates the pseudo-legal moves that GenPseudoLegalCaptures doesn't
+func GenPseudoLegalQuiets(pos *Position) []Move {
+	return genPseudoLegalMovesTo(pos, ^pos.OccupiedBB(), false)
+}
+
+// genPseudoLegalMovesTo generates the moves whose end square is in targetsBB. Pawn moves
+// don't follow the targets, they're split by whether they capture or promote instead.
+func genPseudoLegalMovesTo(pos *Position, targetsBB Bitboard, isCaptures bool) []Move {
+	rtn := make([]Move, 0, 16)
+	color := NewColor(pos.isWhiteTurn)
+	occupiedBB := pos.OccupiedBB()
+	piecesBB := pos.colorBitboards[color]
+	for piecesBB > 0 {
+		var sq Square
+		sq, piecesBB = piecesBB.PopFirstSq()
+		pt := pos.pieces[sq].Type()
+		if pt == PAWN {
+			for _, move := range GenPseudoLegalPawnMoves(pos, sq) {
+				if (pos.IsCapture(move) || move.Type() == PAWN_PROMOTION) == isCaptures {
+					rtn = append(rtn, move)
+				}
+			}
+			continue
+		}
+
+		var attackBB Bitboard
+		if pt == KNIGHT {
+			attackBB = KnightAttacksBB(sq)
+		} else if pt == KING {
+			attackBB = KingAttacksBB(sq)
+		} else {
+			attackBB = SlidingAttacksBB(occupiedBB, sq, pt)
+		}
+		attackBB &= targetsBB
+		for attackBB > 0 {
+			var attackSq Square
+			attackSq, attackBB = attackBB.PopFirstSq()
+			rtn = append(rtn, NewNormalMove(sq, attackSq))
+		}
+		if pt == KING && !isCaptures {
+			for _, move := range GenPseudoLegalKingMoves(pos, sq) {
+				if move.Type() == CASTLING {
+					rtn = append(rtn, move)
+				}
+			}
+		}
+	}
+	return rtn
+}
+
 func GenPseudoLegalPawnMoves(pos *Position, sq Square) []Move {
 	piece := pos.pieces[sq]
 	if DEBUG {
@@ -185,45 +255,40 @@ func GenPseudoLegalKingMoves(pos *Position, sq Square) []Move {
 	return rtn
 }
 
+// SlidingAttacksBB returns the squares attacked by a rook, bishop or queen on the square,
+// with the attacks stopping at the first occupied square in each direction
 func SlidingAttacksBB(occupied Bitboard, sq Square, pt PieceType) Bitboard {
-	initAttackPrecomputes()
-	var rtn Bitboard
-	rank := sq.Rank()
-	if pt == ROOK || pt == QUEEN {
-		file := sq.File()
-		rankMask := BBWithRank(rank, 0b11111111)
-		occupiedRankBB := occupied & rankMask
-		rankAttackBB := rankAttacks[occupiedRankBB][file-1]
-		rtn |= rankAttackBB
-
-		fileMask := BBWithFile(file, 0b11111111)
-		occupiedFileBB := occupied & fileMask
-		fileAttackBB := fileAttacks[occupiedFileBB][rank-1]
-		rtn |= fileAttackBB
-	}
-	if pt == BISHOP || pt == QUEEN {
-		posDiagMask := BBWithPosDiag(sq.PosDiagIdx(), 0b11111111)
-		occupiedPosDiagBB := occupied & posDiagMask
-		rtn |= posDiagAttacks[occupiedPosDiagBB][rank-1]
-
-		negDiagMask := BBWithNegDiag(sq.NegDiagIdx(), 0b11111111)
-		occupiedNegDiagBB := occupied & negDiagMask
-		rtn |= negDiagAttacks[occupiedNegDiagBB][rank-1]
+	switch pt {
+	case ROOK:
+		return rookMagics[sq].AttacksBB(occupied)
+	case BISHOP:
+		return bishopMagics[sq].AttacksBB(occupied)
+	case QUEEN:
+		return rookMagics[sq].AttacksBB(occupied) | bishopMagics[sq].AttacksBB(occupied)
 	}
-	return rtn
+	return 0
+}
+
+// BetweenBB returns the squares strictly between two squares on a shared rank, file or
+// diagonal, or an empty bitboard if the squares aren't aligned
+func BetweenBB(sq1, sq2 Square) Bitboard {
+	return betweenBBs[sq1][sq2]
+}
+
+// LineBB returns the whole rank, file or diagonal through two aligned squares, or an
+// empty bitboard if the squares aren't aligned
+func LineBB(sq1, sq2 Square) Bitboard {
+	return lineBBs[sq1][sq2]
 }
 
 func KnightAttacksBB(sq Square) Bitboard {
-	initAttackPrecomputes()
 	return knightAttacks[sq]
 }
 
 func KingAttacksBB(sq Square) Bitboard {
-	initAttackPrecomputes()
 	return kingAttacks[sq]
 }
 
 func PawnAttacksBB(sq Square, color Color) Bitboard {
-	initAttackPrecomputes()
 	return pawnAttacks[sq][color]
 }
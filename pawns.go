@@ -0,0 +1,145 @@
+package main
+
+const PAWN_STRUCTURE_ENABLED = true
+
+// PAWN_TABLE_SIZE is the number of entries in each search's pawn table. It must be a
+// power of two.
+const PAWN_TABLE_SIZE = 1 << 14
+
+// PawnEntry holds the evaluation of a pawn structure, from white's point of view. The
+// passed pawns of both colors are kept, so that the terms depending on the rest of the
+// position can be added without walking the pawns again.
+type PawnEntry struct {
+	key     ZHash
+	mg      int16
+	eg      int16
+	passers Bitboard
+}
+
+// NewPawnEntry evaluates the pawn structure of the position from scratch
+func NewPawnEntry(pos *Position) PawnEntry {
+	entry := PawnEntry{key: pos.pawnHash}
+	for _, color := range []Color{WHITE, BLACK} {
+		mg, eg, passers := evalPawns(pos, color)
+		if color == WHITE {
+			entry.mg += mg
+			entry.eg += eg
+		} else {
+			entry.mg -= mg
+			entry.eg -= eg
+		}
+		entry.passers |= passers
+	}
+	return entry
+}
+
+// evalPawns scores the color's pawns from its own point of view
+func evalPawns(pos *Position, color Color) (mg, eg int16, passers Bitboard) {
+	ownPawns := pos.pieceBitboards[NewPiece(PAWN, color)]
+	enemyPawns := pos.pieceBitboards[NewPiece(PAWN, color.Opp())]
+	enemyPawnAttacks := allPawnAttacksBB(enemyPawns, color.Opp())
+	for pawnsLeft := ownPawns; pawnsLeft != 0; {
+		var sq Square
+		sq, pawnsLeft = pawnsLeft.PopFirstSq()
+		rank := sq.RelativeRank(color)
+		neighbours := ownPawns & adjacentFilesBBs[sq.File()-1]
+		isDoubled := forwardFileBBs[color][sq]&ownPawns != 0
+
+		if isDoubled {
+			mg += evalParams.DoubledPawnMg
+			eg += evalParams.DoubledPawnEg
+		}
+		if neighbours == 0 {
+			mg += evalParams.IsolatedPawnMg
+			eg += evalParams.IsolatedPawnEg
+		} else if neighbours&^passedPawnMasks[color][sq] == 0 &&
+			enemyPawnAttacks&BBWithSquares(stopSq(sq, color)) != 0 {
+			// every neighbour is ahead, so none can come up to defend the pawn's advance
+			mg += evalParams.BackwardPawnMg
+			eg += evalParams.BackwardPawnEg
+		}
+
+		isSupported := pawnAttacks[sq][color.Opp()]&ownPawns != 0
+		isPhalanx := neighbours&BBWithRank(sq.Rank(), 0b11111111) != 0
+		if isSupported || isPhalanx {
+			mg += evalParams.ConnectedPawnMg[rank]
+			eg += evalParams.ConnectedPawnEg[rank]
+		}
+
+		if !isDoubled && passedPawnMasks[color][sq]&enemyPawns == 0 {
+			mg += evalParams.PassedPawnMg[rank]
+			eg += evalParams.PassedPawnEg[rank]
+			passers |= BBWithSquares(sq)
+		}
+	}
+	return mg, eg, passers
+}
+
+// PasserKingProximity is the endgame bonus for the color's passed pawns whose stop
+// square is close to their own king and far from the enemy king, from the color's point
+// of view. It depends on the kings, so it is not cached with the rest of the pawn
+// structure.
+func PasserKingProximity(pos *Position, passers Bitboard, color Color) int16 {
+	ownKingSq := pos.pieceBitboards[NewPiece(KING, color)].FirstSq()
+	enemyKingSq := pos.pieceBitboards[NewPiece(KING, color.Opp())].FirstSq()
+	if ownKingSq == NULL_SQ || enemyKingSq == NULL_SQ {
+		return 0
+	}
+	var eg int16
+	for passersLeft := passers & pos.pieceBitboards[NewPiece(PAWN, color)]; passersLeft != 0; {
+		var sq Square
+		sq, passersLeft = passersLeft.PopFirstSq()
+		weight := evalParams.PasserKingWeight[sq.RelativeRank(color)]
+		if weight == 0 {
+			continue
+		}
+		stop := stopSq(sq, color)
+		eg += weight * int16(5*stop.DistanceTo(enemyKingSq)-2*stop.DistanceTo(ownKingSq))
+	}
+	return eg
+}
+
+// stopSq is the square in front of the pawn. Pawns are never on the last rank, so the
+// square is always on the board.
+func stopSq(sq Square, color Color) Square {
+	if color == WHITE {
+		return sq + 8
+	}
+	return sq - 8
+}
+
+func allPawnAttacksBB(pawns Bitboard, color Color) Bitboard {
+	if color == WHITE {
+		return (pawns&^FILE_1)<<7 | (pawns&^FILE_8)<<9
+	}
+	return (pawns&^FILE_1)>>9 | (pawns&^FILE_8)>>7
+}
+
+// PawnTable caches pawn structure evaluations by the positions' pawn hash. Pawn
+// structures change rarely during a search, so most lookups hit. A table belongs to a
+// single search thread.
+type PawnTable struct {
+	entries []PawnEntry
+	isSet   []bool
+}
+
+func NewPawnTable() *PawnTable {
+	return &PawnTable{
+		entries: make([]PawnEntry, PAWN_TABLE_SIZE),
+		isSet:   make([]bool, PAWN_TABLE_SIZE),
+	}
+}
+
+// Probe returns the evaluation of the position's pawn structure, evaluating and storing
+// it if the table doesn't have it. A nil table evaluates the structure every time.
+func (pt *PawnTable) Probe(pos *Position) PawnEntry {
+	if pt == nil {
+		return NewPawnEntry(pos)
+	}
+	idx := uint64(pos.pawnHash) & (PAWN_TABLE_SIZE - 1)
+	if !pt.isSet[idx] || pt.entries[idx].key != pos.pawnHash {
+		pt.entries[idx] = NewPawnEntry(pos)
+		pt.isSet[idx] = true
+	}
+	return pt.entries[idx]
+}
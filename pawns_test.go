@@ -0,0 +1,96 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func evalPawnsFromFEN(fen string, color Color) (mg, eg int16, passers Bitboard) {
+	pos, posErr := FromFEN(fen)
+	Expect(posErr).ToNot(HaveOccurred())
+	return evalPawns(pos, color)
+}
+
+var _ = Describe("Pawn structure", func() {
+	Describe("#evalPawns", func() {
+		It("penalizes isolated pawns", func() {
+			mg, eg, _ := evalPawnsFromFEN("4k3/pppp4/8/8/8/8/P7/4K3 w - - 0 1", WHITE)
+			Expect(mg).To(Equal(evalParams.IsolatedPawnMg))
+			Expect(eg).To(Equal(evalParams.IsolatedPawnEg))
+		})
+		It("penalizes doubled pawns and only counts the front one as passed", func() {
+			_, _, passers := evalPawnsFromFEN("4k3/8/8/8/8/1P6/1P6/4K3 w - - 0 1", WHITE)
+			Expect(passers).To(Equal(BBWithSquares(SQ_B3)))
+			mg, _, _ := evalPawnsFromFEN("4k3/8/8/8/8/1P6/1P6/4K3 w - - 0 1", WHITE)
+			Expect(mg).To(Equal(evalParams.DoubledPawnMg + 2*evalParams.IsolatedPawnMg + evalParams.PassedPawnMg[3]))
+		})
+		It("penalizes backward pawns", func() {
+			mg, _, _ := evalPawnsFromFEN("4k3/8/1p6/3p4/1P6/2P5/8/4K3 w - - 0 1", WHITE)
+			// c3 is backward, and supports b4
+			Expect(mg).To(Equal(evalParams.BackwardPawnMg + evalParams.ConnectedPawnMg[4]))
+		})
+		It("rewards connected pawns by rank", func() {
+			mg, _, _ := evalPawnsFromFEN("4k3/pp6/8/3PP3/8/8/8/4K3 w - - 0 1", WHITE)
+			Expect(mg).To(Equal(2*evalParams.ConnectedPawnMg[5] + 2*evalParams.PassedPawnMg[5]))
+		})
+		It("finds passed pawns of either color", func() {
+			_, _, whitePassers := evalPawnsFromFEN("4k3/p7/8/1P4p1/8/8/6P1/4K3 w - - 0 1", WHITE)
+			Expect(whitePassers).To(BeZero())
+			_, _, blackPassers := evalPawnsFromFEN("4k3/p7/8/6p1/8/8/8/4K3 w - - 0 1", BLACK)
+			Expect(blackPassers).To(Equal(BBWithSquares(SQ_A7, SQ_G5)))
+		})
+	})
+	Describe("#PasserKingProximity", func() {
+		It("rewards a passer escorted by its king", func() {
+			escorted, _ := FromFEN("8/8/1k4K1/6P1/8/8/8/8 w - - 0 1")
+			caught, _ := FromFEN("8/8/6k1/6P1/8/8/8/1K6 w - - 0 1")
+			passers := BBWithSquares(SQ_G5)
+			Expect(PasserKingProximity(escorted, passers, WHITE)).To(BeNumerically(">", 0))
+			Expect(PasserKingProximity(caught, passers, WHITE)).To(BeNumerically("<", 0))
+			Expect(PasserKingProximity(escorted, passers, BLACK)).To(BeZero())
+		})
+	})
+	Describe("::pawnHash", func() {
+		It("stays equal to a full recount as moves are made and unmade", func() {
+			pos, _ := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+			var walk func(depth int)
+			walk = func(depth int) {
+				Expect(pos.pawnHash).To(Equal(NewPawnZHash(pos)))
+				if depth == 0 {
+					return
+				}
+				for _, move := range GenLegalMoves(pos) {
+					captured, lastFrozenPos := pos.MakeMove(move)
+					walk(depth - 1)
+					pos.UnmakeMove(move, lastFrozenPos, captured)
+				}
+			}
+			walk(2)
+		})
+		It("ignores pieces other than pawns", func() {
+			pos, _ := FromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+			pawnHash := pos.pawnHash
+			_, _ = pos.MakeMove(NewNormalMove(SQ_G1, SQ_F3))
+			Expect(pos.pawnHash).To(Equal(pawnHash))
+			_, _ = pos.MakeMove(NewNormalMove(SQ_E7, SQ_E5))
+			Expect(pos.pawnHash).ToNot(Equal(pawnHash))
+		})
+	})
+	Describe("PawnTable", func() {
+		It("returns the same entry as a fresh evaluation", func() {
+			table := NewPawnTable()
+			pos, _ := FromFEN("4k3/p7/8/1P4p1/8/8/6P1/4K3 w - - 0 1")
+			Expect(table.Probe(pos)).To(Equal(NewPawnEntry(pos)))
+			// the second probe is served from the table
+			Expect(table.Probe(pos)).To(Equal(NewPawnEntry(pos)))
+		})
+		It("replaces an entry when another structure maps to the same index", func() {
+			table := NewPawnTable()
+			pos, _ := FromFEN("4k3/p7/8/1P4p1/8/8/6P1/4K3 w - - 0 1")
+			other, _ := FromFEN("4k3/8/8/8/8/8/PPP5/4K3 w - - 0 1")
+			other.pawnHash = pos.pawnHash ^ PAWN_TABLE_SIZE
+			_ = table.Probe(pos)
+			Expect(table.Probe(other)).To(Equal(NewPawnEntry(other)))
+		})
+	})
+})
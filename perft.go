@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// perftSlot stores the node count of a position at some depth. The hash is stored xor'd
+// with the data, so that a slot written concurrently by another thread is detected as a
+// miss. The low 8 bits of the data hold the depth, the rest hold the node count.
+type perftSlot struct {
+	check atomic.Uint64
+	data  atomic.Uint64
+}
+
+// PerftTable caches the node counts of positions already counted, as the same position
+// is usually reached through several move orders. It may be shared by several threads.
+type PerftTable struct {
+	slots []perftSlot
+	mask  uint64
+}
+
+// NewPerftTable allocates the largest power of two number of slots that fits in sizeMb
+// megabytes
+func NewPerftTable(sizeMb int) *PerftTable {
+	maxSlots := uint64(MaxInt(1, sizeMb)) * 1024 * 1024 / uint64(unsafe.Sizeof(perftSlot{}))
+	nSlots := uint64(1)
+	for nSlots*2 <= maxSlots {
+		nSlots *= 2
+	}
+	return &PerftTable{
+		slots: make([]perftSlot, nSlots),
+		mask:  nSlots - 1,
+	}
+}
+
+func (pt *PerftTable) Get(hash ZHash, depth int) (nodeCnt uint64, exists bool) {
+	slot := &pt.slots[uint64(hash)&pt.mask]
+	data := slot.data.Load()
+	if slot.check.Load()^data != uint64(hash) || data&0xFF != uint64(depth) {
+		return 0, false
+	}
+	return data >> 8, true
+}
+
+func (pt *PerftTable) Put(hash ZHash, depth int, nodeCnt uint64) {
+	slot := &pt.slots[uint64(hash)&pt.mask]
+	data := nodeCnt<<8 | uint64(depth)
+	slot.check.Store(uint64(hash) ^ data)
+	slot.data.Store(data)
+}
+
+// Perft counts the leaf nodes of the legal move tree to the given depth. The moves at the
+// last ply are counted without being made. The table may be nil.
+func Perft(pos *Position, depth int, table *PerftTable) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	moves := GenLegalMoves(pos)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	if table != nil {
+		if nodeCnt, exists := table.Get(pos.hash, depth); exists {
+			return nodeCnt
+		}
+	}
+	var nodeCnt uint64
+	for _, move := range moves {
+		captured, lastFrozenPos := pos.MakeMove(move)
+		nodeCnt += Perft(pos, depth-1, table)
+		pos.UnmakeMove(move, lastFrozenPos, captured)
+	}
+	if table != nil {
+		table.Put(pos.hash, depth, nodeCnt)
+	}
+	return nodeCnt
+}
+
+// PerftDivide is the node count under a single root move
+type PerftDivide struct {
+	Move    Move
+	NodeCnt uint64
+}
+
+// PerftDivided runs Perft under each root move, with the root moves split across the given
+// number of threads. The divides are returned in move generation order.
+func PerftDivided(pos *Position, depth int, threads int, table *PerftTable) []PerftDivide {
+	moves := GenLegalMoves(pos)
+	divides := make([]PerftDivide, len(moves))
+	if depth == 0 {
+		return divides[:0]
+	}
+	var nextMoveIdx atomic.Int64
+	wg := sync.WaitGroup{}
+	for thread := 0; thread < MaxInt(1, threads); thread++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			threadPos := pos.Copy()
+			for {
+				moveIdx := int(nextMoveIdx.Add(1) - 1)
+				if moveIdx >= len(moves) {
+					return
+				}
+				move := moves[moveIdx]
+				captured, lastFrozenPos := threadPos.MakeMove(move)
+				divides[moveIdx] = PerftDivide{move, Perft(threadPos, depth-1, table)}
+				threadPos.UnmakeMove(move, lastFrozenPos, captured)
+			}
+		}()
+	}
+	wg.Wait()
+	return divides
+}
+
+// RunPerft prints the node count under each root move, followed by the total. The format
+// matches other engines' output, so that counts can be diffed to find move generation bugs.
+func RunPerft(pos *Position, depth int, threads int, table *PerftTable) uint64 {
+	start := time.Now()
+	var nodeCnt uint64
+	for _, divide := range PerftDivided(pos, depth, threads, table) {
+		fmt.Printf("%s: %d\n", divide.Move, divide.NodeCnt)
+		nodeCnt += divide.NodeCnt
+	}
+	if depth == 0 {
+		nodeCnt = 1
+	}
+	elapsed := time.Since(start)
+	var nps int64
+	if elapsed > 0 {
+		nps = int64(float64(nodeCnt) / elapsed.Seconds())
+	}
+	fmt.Println()
+	fmt.Println("Nodes searched:", nodeCnt)
+	fmt.Printf("info time %d nps %d\n", elapsed.Milliseconds(), nps)
+	return nodeCnt
+}
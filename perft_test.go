@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/CameronHonis/Mila"
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 	"log"
 	"os"
 	"strconv"
@@ -132,3 +133,39 @@ var _ = It("perft", func() {
 
 	perftFromFile()
 })
+
+var _ = Describe("Perft", func() {
+	const KIWIPETE_FEN = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	var pos *main.Position
+	BeforeEach(func() {
+		var posErr error
+		pos, posErr = main.FromFEN(KIWIPETE_FEN)
+		Expect(posErr).ToNot(HaveOccurred())
+	})
+	It("counts the leaf nodes", func() {
+		Expect(main.Perft(pos, 0, nil)).To(Equal(uint64(1)))
+		Expect(main.Perft(pos, 1, nil)).To(Equal(uint64(48)))
+		Expect(main.Perft(pos, 3, nil)).To(Equal(uint64(97862)))
+	})
+	It("counts the same with a table caching transposed positions", func() {
+		table := main.NewPerftTable(1)
+		Expect(main.Perft(pos, 3, table)).To(Equal(uint64(97862)))
+		// the second count is served from the table
+		Expect(main.Perft(pos, 3, table)).To(Equal(uint64(97862)))
+		Expect(main.Perft(pos, 2, table)).To(Equal(uint64(2039)))
+	})
+	It("divides the count among the root moves, regardless of the number of threads", func() {
+		serialDivides := main.PerftDivided(pos, 3, 1, nil)
+		Expect(serialDivides).To(HaveLen(48))
+		var nodeCnt uint64
+		for _, divide := range serialDivides {
+			nodeCnt += divide.NodeCnt
+		}
+		Expect(nodeCnt).To(Equal(uint64(97862)))
+		Expect(main.PerftDivided(pos, 3, 4, main.NewPerftTable(1))).To(Equal(serialDivides))
+	})
+	It("leaves the position as it was", func() {
+		main.PerftDivided(pos, 2, 2, nil)
+		Expect(pos.FEN()).To(Equal(KIWIPETE_FEN))
+	})
+})
@@ -0,0 +1,108 @@
+package main
+
+import "math/bits"
+
+const MOBILITY_ENABLED = true
+const PIECES_ENABLED = true
+
+// The mobility of a piece is scored by how many more squares it reaches than it
+// typically does, weighted per square by the type of piece. The baselines are not eval
+// params: moving one only shifts the score of every piece of that type by the same
+// amount, which the piece values already cover.
+var mobilityBaselines = [N_PIECE_TYPES]int16{KNIGHT: 4, BISHOP: 6, ROOK: 7, QUEEN: 13}
+
+// EvalMobility scores how freely the color's pieces move, from the color's point of view.
+// Squares held by the color's own pieces or attacked by enemy pawns don't count, as
+// pieces can rarely make use of them.
+func EvalMobility(pos *Position, color Color) TaperedScore {
+	occupied := pos.OccupiedBB()
+	enemyPawnAttacks := allPawnAttacksBB(pos.pieceBitboards[NewPiece(PAWN, color.Opp())], color.Opp())
+	availableBB := ^(pos.colorBitboards[color] | enemyPawnAttacks)
+	var score TaperedScore
+	for _, pt := range []PieceType{KNIGHT, BISHOP, ROOK, QUEEN} {
+		for piecesLeft := pos.pieceBitboards[NewPiece(pt, color)]; piecesLeft != 0; {
+			var sq Square
+			sq, piecesLeft = piecesLeft.PopFirstSq()
+			var attacksBB Bitboard
+			if pt == KNIGHT {
+				attacksBB = KnightAttacksBB(sq)
+			} else {
+				attacksBB = SlidingAttacksBB(occupied, sq, pt)
+			}
+			mobility := int16(bits.OnesCount64(uint64(attacksBB&availableBB))) - mobilityBaselines[pt]
+			score.mg += mobility * evalParams.MobilityMg[pt]
+			score.eg += mobility * evalParams.MobilityEg[pt]
+		}
+	}
+	return score
+}
+
+// EvalPieces scores the placement of the color's pieces beyond their piece-square
+// bonuses, from the color's point of view: the bishop pair, rooks on open files and on the
+// 7th rank, and minor pieces on outposts
+func EvalPieces(pos *Position, color Color) TaperedScore {
+	var score TaperedScore
+	if pos.material.HasBishopPair(color) {
+		score = score.Add(TaperedScore{evalParams.BishopPairMg, evalParams.BishopPairEg})
+	}
+	score = score.Add(evalRooks(pos, color))
+	score = score.Add(evalOutposts(pos, color))
+	return score
+}
+
+func evalRooks(pos *Position, color Color) TaperedScore {
+	ownPawns := pos.pieceBitboards[NewPiece(PAWN, color)]
+	enemyPawns := pos.pieceBitboards[NewPiece(PAWN, color.Opp())]
+	enemyKingSq := pos.pieceBitboards[NewPiece(KING, color.Opp())].FirstSq()
+	var score TaperedScore
+	for rooksLeft := pos.pieceBitboards[NewPiece(ROOK, color)]; rooksLeft != 0; {
+		var sq Square
+		sq, rooksLeft = rooksLeft.PopFirstSq()
+		fileBB := BBWithFile(sq.File(), 0b11111111)
+		if fileBB&ownPawns == 0 {
+			if fileBB&enemyPawns == 0 {
+				score = score.Add(TaperedScore{evalParams.RookOpenFileMg, evalParams.RookOpenFileEg})
+			} else {
+				score = score.Add(TaperedScore{evalParams.RookSemiOpenFileMg, evalParams.RookSemiOpenFileEg})
+			}
+		}
+		// the 7th rank is only worth taking if there are pawns to eat or a king to cut off
+		if sq.RelativeRank(color) == 7 {
+			rankBB := BBWithRank(sq.Rank(), 0b11111111)
+			isKingCutOff := enemyKingSq != NULL_SQ && enemyKingSq.RelativeRank(color) == 8
+			if rankBB&enemyPawns != 0 || isKingCutOff {
+				score = score.Add(TaperedScore{evalParams.RookOn7thMg, evalParams.RookOn7thEg})
+			}
+		}
+	}
+	return score
+}
+
+// evalOutposts rewards knights and bishops in the enemy half of the board that are
+// defended by a pawn and can never be chased away by an enemy pawn
+func evalOutposts(pos *Position, color Color) TaperedScore {
+	ownPawns := pos.pieceBitboards[NewPiece(PAWN, color)]
+	enemyPawns := pos.pieceBitboards[NewPiece(PAWN, color.Opp())]
+	var score TaperedScore
+	for _, pt := range []PieceType{KNIGHT, BISHOP} {
+		for piecesLeft := pos.pieceBitboards[NewPiece(pt, color)]; piecesLeft != 0; {
+			var sq Square
+			sq, piecesLeft = piecesLeft.PopFirstSq()
+			rank := sq.RelativeRank(color)
+			if rank < 4 || rank > 6 {
+				continue
+			}
+			isDefended := PawnAttacksBB(sq, color.Opp())&ownPawns != 0
+			chasersBB := passedPawnMasks[color][sq] & adjacentFilesBBs[sq.File()-1] & enemyPawns
+			if !isDefended || chasersBB != 0 {
+				continue
+			}
+			if pt == KNIGHT {
+				score = score.Add(TaperedScore{evalParams.KnightOutpostMg, evalParams.KnightOutpostEg})
+			} else {
+				score = score.Add(TaperedScore{evalParams.BishopOutpostMg, evalParams.BishopOutpostEg})
+			}
+		}
+	}
+	return score
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func posFromFEN(fen string) *Position {
+	pos, posErr := FromFEN(fen)
+	Expect(posErr).ToNot(HaveOccurred())
+	return pos
+}
+
+var _ = Describe("Piece evaluation", func() {
+	Describe("#EvalMobility", func() {
+		It("scores each piece against its typical mobility", func() {
+			// the knight reaches 8 squares, 4 more than its baseline
+			score := EvalMobility(posFromFEN("4k3/8/8/8/3N4/8/8/4K3 w - - 0 1"), WHITE)
+			Expect(score).To(Equal(TaperedScore{4 * evalParams.MobilityMg[KNIGHT], 4 * evalParams.MobilityEg[KNIGHT]}))
+		})
+		It("ignores squares held by its own pieces or attacked by enemy pawns", func() {
+			free := EvalMobility(posFromFEN("4k3/8/8/8/3N4/8/8/4K3 w - - 0 1"), WHITE)
+			restricted := EvalMobility(posFromFEN("4k3/3p4/8/8/3N4/8/2P5/4K3 w - - 0 1"), WHITE)
+			// c6, e6 are attacked by the pawn on d7 and c2 holds a white pawn
+			Expect(restricted.mg).To(Equal(free.mg - 3*evalParams.MobilityMg[KNIGHT]))
+		})
+		It("stops sliders at blockers", func() {
+			open := EvalMobility(posFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1"), WHITE)
+			blocked := EvalMobility(posFromFEN("4k3/8/8/8/8/8/P7/RN2K3 w - - 0 1"), WHITE)
+			Expect(blocked.mg).To(BeNumerically("<", open.mg))
+		})
+	})
+	Describe("#EvalPieces", func() {
+		It("rewards the bishop pair only with bishops on both colors", func() {
+			pair := EvalPieces(posFromFEN("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1"), WHITE)
+			Expect(pair).To(Equal(TaperedScore{evalParams.BishopPairMg, evalParams.BishopPairEg}))
+			sameColor := EvalPieces(posFromFEN("4k3/8/8/8/8/8/4B3/4KB2 w - - 0 1"), WHITE)
+			Expect(sameColor).To(Equal(TaperedScore{}))
+		})
+		It("rewards rooks on open and semi-open files", func() {
+			open := EvalPieces(posFromFEN("4k3/pp6/8/8/8/8/PP6/3RK3 w - - 0 1"), WHITE)
+			Expect(open).To(Equal(TaperedScore{evalParams.RookOpenFileMg, evalParams.RookOpenFileEg}))
+			semiOpen := EvalPieces(posFromFEN("4k3/3p4/8/8/8/8/8/3RK3 w - - 0 1"), WHITE)
+			Expect(semiOpen).To(Equal(TaperedScore{evalParams.RookSemiOpenFileMg, evalParams.RookSemiOpenFileEg}))
+			closed := EvalPieces(posFromFEN("4k3/8/8/8/8/8/3P4/3RK3 w - - 0 1"), WHITE)
+			Expect(closed).To(Equal(TaperedScore{}))
+		})
+		It("rewards rooks on the 7th rank with pawns to eat or a king to cut off", func() {
+			onSeventh := EvalPieces(posFromFEN("7k/1R4pp/8/8/8/8/1P6/4K3 w - - 0 1"), WHITE)
+			Expect(onSeventh).To(Equal(TaperedScore{evalParams.RookOn7thMg, evalParams.RookOn7thEg}))
+			pointless := EvalPieces(posFromFEN("8/1R6/7k/8/8/8/1P6/4K3 w - - 0 1"), WHITE)
+			Expect(pointless).To(Equal(TaperedScore{}))
+		})
+		It("rewards minor pieces on outposts", func() {
+			outpost := EvalPieces(posFromFEN("4k3/p7/8/4N3/3P4/8/8/4K3 w - - 0 1"), WHITE)
+			Expect(outpost).To(Equal(TaperedScore{evalParams.KnightOutpostMg, evalParams.KnightOutpostEg}))
+			// the f7 pawn can still chase the knight away
+			chased := EvalPieces(posFromFEN("4k3/5p2/8/4N3/3P4/8/8/4K3 w - - 0 1"), WHITE)
+			Expect(chased).To(Equal(TaperedScore{}))
+			undefended := EvalPieces(posFromFEN("4k3/p7/8/4N3/8/8/3P4/4K3 w - - 0 1"), WHITE)
+			Expect(undefended).To(Equal(TaperedScore{}))
+		})
+		It("scores black's pieces from black's side of the board", func() {
+			Expect(EvalPieces(posFromFEN("4k3/8/2p5/3n4/3P4/8/P7/4K3 b - - 0 1"), BLACK)).
+				To(Equal(TaperedScore{evalParams.KnightOutpostMg, evalParams.KnightOutpostEg}))
+		})
+	})
+})
@@ -25,9 +25,11 @@ type Position struct {
 	pieceBitboards [N_PIECES]Bitboard
 	colorBitboards [N_COLORS]Bitboard
 	material       Material
+	pst            PSTScore
 	repetitions    map[ZHash]uint8
 	ply            Ply
 	hash           ZHash
+	pawnHash       ZHash
 	result         Result // only covers non-checkmate/stalemate positions
 	isWhiteTurn    bool
 
@@ -76,6 +78,8 @@ func InitPos() *Position {
 		},
 	}
 	pos.hash = NewZHash(pos)
+	pos.pawnHash = NewPawnZHash(pos)
+	pos.pst = NewPSTScore(pos)
 	return pos
 }
 
@@ -181,10 +185,23 @@ func FromFEN(fen string) (*Position, error) {
 	}
 
 	pos.hash = NewZHash(pos)
+	pos.pawnHash = NewPawnZHash(pos)
+	pos.pst = NewPSTScore(pos)
 
 	return pos, nil
 }
 
+// Copy returns a deep copy of the position, which can be mutated independently
+func (p *Position) Copy() *Position {
+	posCopy := *p
+	posCopy.repetitions = make(map[ZHash]uint8, len(p.repetitions))
+	for hash, cnt := range p.repetitions {
+		posCopy.repetitions[hash] = cnt
+	}
+	posCopy.frozenPos = p.frozenPos.Copy()
+	return &posCopy
+}
+
 func (p *Position) String() string {
 	var rtnBuilder = strings.Builder{}
 	fenPieces := strings.Split(p.FEN(), " ")
@@ -303,33 +320,10 @@ func (p *Position) OccupiedBB() Bitboard {
 	return rtn
 }
 
-// IsLegalMove is intended to filter out only valid pseudo-legal moves.
+// IsLegalMove is intended to filter out only valid pseudo-legal moves. The checkers and
+// pins are found from scratch, so use a Legality to check many moves of one position.
 func (p *Position) IsLegalMove(pMove Move) bool {
-	piece := p.pieces[pMove.StartSq()]
-	pt := piece.Type()
-	color := piece.Color()
-
-	if pt == KING && pMove.Type() == CASTLING {
-		start := pMove.StartSq()
-		end := pMove.EndSq()
-		sq0 := start
-		var sq1 Square
-		if end > start {
-			sq1 = sq0 + 1
-		} else {
-			sq1 = sq0 - 1
-		}
-		sq2 := end
-		return !p.isSquareAttacked(color.Opp(), sq0) &&
-			!p.isSquareAttacked(color.Opp(), sq1) &&
-			!p.isSquareAttacked(color.Opp(), sq2)
-	} else {
-		capturedPiece, fPos := p.MakeMove(pMove)
-		kingSq := p.pieceBitboards[NewPiece(KING, color)].FirstSq()
-		defer p.UnmakeMove(pMove, fPos, capturedPiece)
-
-		return !p.isSquareAttacked(color.Opp(), kingSq)
-	}
+	return NewLegality(p).IsLegal(pMove)
 }
 
 // MakeMove expects the inbound move to be filtered by Position.IsLegalMove
@@ -414,6 +408,41 @@ func (p *Position) UnmakeMove(move Move, fp *FrozenPos, captured Piece) {
 	p.hash = p.hash.ToggleTurn()
 }
 
+// MakeNullMove passes the turn to the opponent without moving a piece. Null moves are
+// not legal chess moves, they are only used to prune the search, so they aren't counted
+// as repetitions.
+func (p *Position) MakeNullMove() (lastFrozenPos *FrozenPos) {
+	p.ply++
+
+	lastFrozenPos = p.frozenPos
+	p.frozenPos = lastFrozenPos.Copy()
+	p.frozenPos.EnPassantSq = NULL_SQ
+	p.frozenPos.Rule50++
+	if lastFrozenPos.EnPassantSq != NULL_SQ {
+		p.hash = p.hash.UpdateEnPassantSq(lastFrozenPos.EnPassantSq, NULL_SQ)
+	}
+
+	p.isWhiteTurn = !p.isWhiteTurn
+	p.hash = p.hash.ToggleTurn()
+	return
+}
+
+func (p *Position) UnmakeNullMove(fp *FrozenPos) {
+	if fp.EnPassantSq != p.frozenPos.EnPassantSq {
+		p.hash = p.hash.UpdateEnPassantSq(p.frozenPos.EnPassantSq, fp.EnPassantSq)
+	}
+	p.frozenPos = fp
+
+	p.ply--
+
+	p.isWhiteTurn = !p.isWhiteTurn
+	p.hash = p.hash.ToggleTurn()
+}
+
+func (p *Position) IsCapture(move Move) bool {
+	return move.Type() == CAPTURES_EN_PASSANT || p.pieces[move.EndSq()] != EMPTY
+}
+
 func (p *Position) IsKingChecked() bool {
 	color := NewColor(p.isWhiteTurn)
 	piece := NewPiece(KING, color)
@@ -430,6 +459,44 @@ func (p *Position) HasLegalMoves() bool {
 	return !hasNoLegalMoves
 }
 
+func (p *Position) HasLegalMove(move Move) bool {
+	iter := NewLegalMoveIter(p)
+	for {
+		legalMove, done := iter.Next()
+		if done {
+			return false
+		}
+		if legalMove == move {
+			return true
+		}
+	}
+}
+
+// IsPseudoLegalMove returns true if the move would be generated for this position, without
+// checking that it leaves the king safe. Only the moving piece's moves are generated.
+func (p *Position) IsPseudoLegalMove(move Move) bool {
+	start := move.StartSq()
+	piece := p.pieces[start]
+	if piece == EMPTY || piece.IsWhite() != p.isWhiteTurn {
+		return false
+	}
+	var pMoves []Move
+	pt := piece.Type()
+	if pt == PAWN {
+		pMoves = GenPseudoLegalPawnMoves(p, start)
+	} else if pt == KING {
+		pMoves = GenPseudoLegalKingMoves(p, start)
+	} else {
+		pMoves = GenPseudoLegalNimblePieceMoves(p, start)
+	}
+	for _, pMove := range pMoves {
+		if pMove == move {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Position) doCastle(move Move) {
 	start := move.StartSq()
 	end := move.EndSq()
@@ -515,7 +582,11 @@ func (p *Position) removePiece(sq Square) (removed Piece) {
 		p.pieceBitboards[EMPTY] ^= mask
 		p.colorBitboards[color] ^= mask
 		p.material.RemovePiece(piece, sq)
+		p.pst.RemovePiece(piece, sq)
 		p.hash = p.hash.UpdatePieceOnSq(piece, EMPTY, sq)
+		if piece.Type() == PAWN {
+			p.pawnHash = p.pawnHash.UpdatePieceOnSq(piece, EMPTY, sq)
+		}
 	}
 	return piece
 }
@@ -535,8 +606,14 @@ func (p *Position) movePiece(startSq, endSq Square) (captured Piece) {
 		p.pieceBitboards[piece] ^= startMask | endMask
 		p.pieceBitboards[EMPTY] ^= startMask | endMask
 		p.colorBitboards[color] ^= startMask | endMask
+		p.pst.RemovePiece(piece, startSq)
+		p.pst.AddPiece(piece, endSq)
 		p.hash = p.hash.UpdatePieceOnSq(piece, EMPTY, startSq)
 		p.hash = p.hash.UpdatePieceOnSq(EMPTY, piece, endSq)
+		if piece.Type() == PAWN {
+			p.pawnHash = p.pawnHash.UpdatePieceOnSq(piece, EMPTY, startSq)
+			p.pawnHash = p.pawnHash.UpdatePieceOnSq(EMPTY, piece, endSq)
+		}
 	}
 	return
 }
@@ -554,7 +631,11 @@ func (p *Position) addPiece(sq Square, piece Piece) {
 		p.pieceBitboards[piece] ^= mask
 		p.colorBitboards[NewColor(piece.IsWhite())] ^= mask
 		p.material.AddPiece(piece, sq)
+		p.pst.AddPiece(piece, sq)
 		p.hash = p.hash.UpdatePieceOnSq(EMPTY, piece, sq)
+		if piece.Type() == PAWN {
+			p.pawnHash = p.pawnHash.UpdatePieceOnSq(EMPTY, piece, sq)
+		}
 	}
 }
 
@@ -81,6 +81,16 @@ var _ = Describe("Position", func() {
 			Expect(pos.FEN()).To(Equal("4k3/3ppp2/8/2Q5/6N1/5K2/8/8 w - - 0 1"))
 		})
 	})
+	Describe("::Copy", func() {
+		It("is unaffected by moves made on the original", func() {
+			pos := InitPos()
+			posCopy := pos.Copy()
+			_, _ = pos.MakeMove(NewNormalMove(SQ_E2, SQ_E4))
+			Expect(posCopy.FEN()).To(Equal(InitPos().FEN()))
+			Expect(posCopy.repetitions).To(Equal(InitPos().repetitions))
+			Expect(posCopy.hash).To(Equal(InitPos().hash))
+		})
+	})
 	Describe("::MakeMove", func() {
 		var pos *Position
 		var prevHash ZHash
@@ -359,6 +369,30 @@ var _ = Describe("Position", func() {
 			})
 		})
 	})
+	Describe("::MakeNullMove + ::UnmakeNullMove", func() {
+		var pos *Position
+		BeforeEach(func() {
+			var posErr error
+			pos, posErr = FromFEN("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2")
+			Expect(posErr).ToNot(HaveOccurred())
+		})
+		It("passes the turn and clears the en passant square", func() {
+			_ = pos.MakeNullMove()
+			Expect(pos.isWhiteTurn).To(BeFalse())
+			Expect(pos.frozenPos.EnPassantSq).To(Equal(NULL_SQ))
+			Expect(pos.hash).To(Equal(NewZHash(pos)))
+		})
+		It("restores the position", func() {
+			fen := pos.FEN()
+			hash := pos.hash
+			ply := pos.ply
+			lastFrozenPos := pos.MakeNullMove()
+			pos.UnmakeNullMove(lastFrozenPos)
+			Expect(pos.FEN()).To(Equal(fen))
+			Expect(pos.hash).To(Equal(hash))
+			Expect(pos.ply).To(Equal(ply))
+		})
+	})
 	Describe("::IsLegalMove", func() {
 		When("the move is a king move", func() {
 			When("the king is in check and 'walks the line of sight'", func() {
@@ -1,176 +1,25 @@
 package main
 
-// N_ROW_PERMUS is the number of possible permutations of occupied squares
-// for any row (2^8). This number happens to be the permutations of occupied
-// squares for columns or diagonals as well.
-const N_ROW_PERMUS = 1 << 8
-
-// Splitting the precomputed rook moves into rows and columns as separate look tables
-// reduces the overall amount of entries in the set. This works out since a rooks moves
-// on a given row have no dependence to its moves on a file, and vice versa.
-
-var rankAttacks map[Bitboard][N_FILES]Bitboard
-var fileAttacks map[Bitboard][N_RANKS]Bitboard
-var posDiagAttacks map[Bitboard][N_RANKS]Bitboard
-var negDiagAttacks map[Bitboard][N_RANKS]Bitboard
 var pawnAttacks [N_SQUARES][N_COLORS]Bitboard
 var knightAttacks [N_SQUARES]Bitboard
 var kingAttacks [N_SQUARES]Bitboard
-
-var isInitted = false
+var betweenBBs [N_SQUARES][N_SQUARES]Bitboard
+var lineBBs [N_SQUARES][N_SQUARES]Bitboard
+var adjacentFilesBBs [N_FILES]Bitboard
+var forwardFileBBs [N_COLORS][N_SQUARES]Bitboard
+var passedPawnMasks [N_COLORS][N_SQUARES]Bitboard
+
+func init() {
+	initAttackPrecomputes()
+	initPawnMasks()
+}
 
 func initAttackPrecomputes() {
-	if isInitted {
-		return
-	}
-	initRankAttacks()
-	initFileAttacks()
-	initPosDiagAttacks()
-	initNegDiagAttacks()
+	initMagics()
 	initPawnAttacks()
 	initKnightAttacks()
 	initKingAttacks()
-	isInitted = true
-}
-
-func initRankAttacks() {
-	rankAttacks = make(map[Bitboard][N_FILES]Bitboard)
-	for _, occupiedBB := range genRankOccupiedBBs() {
-		rank := occupiedBB.FirstSq().Rank()
-		attackBBs := [N_FILES]Bitboard{}
-		for file := uint8(1); file <= N_FILES; file++ {
-			var attackBB Bitboard
-			sq := SqFromCoords(int(rank), int(file))
-			leftProbe := sq
-			for leftProbe.File() > 1 {
-				leftProbe--
-				mask := BBWithHighBitsAt(int(leftProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			rightProbe := sq
-			for rightProbe.File() < 8 {
-				rightProbe++
-				mask := BBWithHighBitsAt(int(rightProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			attackBBs[file-1] = attackBB
-		}
-		rankAttacks[occupiedBB] = attackBBs
-	}
-}
-
-func initFileAttacks() {
-	fileAttacks = make(map[Bitboard][N_RANKS]Bitboard)
-	for _, occupiedBB := range genFileOccupiedBBs() {
-		file := occupiedBB.FirstSq().File()
-		attackBBs := [N_RANKS]Bitboard{}
-		for rank := uint8(1); rank <= N_RANKS; rank++ {
-			var attackBB Bitboard
-			sq := SqFromCoords(int(rank), int(file))
-			upProbe := sq
-			for upProbe.Rank() < 8 {
-				upProbe += 8
-				mask := BBWithHighBitsAt(int(upProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			downProbe := sq
-			for downProbe.Rank() > 1 {
-				downProbe -= 8
-				mask := BBWithHighBitsAt(int(downProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			attackBBs[rank-1] = attackBB
-		}
-		fileAttacks[occupiedBB] = attackBBs
-	}
-}
-
-func initPosDiagAttacks() {
-	posDiagAttacks = make(map[Bitboard][N_RANKS]Bitboard)
-	for _, occupiedBB := range genPosDiagOccupiedBBs() {
-		diagIdx := occupiedBB.FirstSq().PosDiagIdx()
-		diagMask := BBWithPosDiag(diagIdx, 0b11111111)
-		attackBBs := [N_RANKS]Bitboard{}
-		for rank := uint8(1); rank <= N_RANKS; rank++ {
-			var attackBB Bitboard
-			rankMask := BBWithRank(rank, 0b11111111)
-			sq := (diagMask & rankMask).FirstSq()
-			if sq == NULL_SQ {
-				attackBBs[rank-1] = attackBB
-				continue
-			}
-			downLeftProbe := sq
-			for downLeftProbe.Rank() > 1 && downLeftProbe.File() > 1 {
-				downLeftProbe -= 9
-				mask := BBWithHighBitsAt(int(downLeftProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			upRightProbe := sq
-			for upRightProbe.Rank() < 8 && upRightProbe.File() < 8 {
-				upRightProbe += 9
-				mask := BBWithHighBitsAt(int(upRightProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			attackBBs[rank-1] = attackBB
-		}
-		posDiagAttacks[occupiedBB] = attackBBs
-	}
-}
-
-func initNegDiagAttacks() {
-	negDiagAttacks = make(map[Bitboard][N_RANKS]Bitboard)
-	for _, occupiedBB := range genNegDiagOccupiedBBs() {
-		diagIdx := occupiedBB.FirstSq().NegDiagIdx()
-		diagMask := BBWithNegDiag(diagIdx, 0b11111111)
-		attackBBs := [N_RANKS]Bitboard{}
-		for rank := uint8(1); rank <= N_RANKS; rank++ {
-			var attackBB Bitboard
-			rankMask := BBWithRank(rank, 0b11111111)
-			sq := (diagMask & rankMask).FirstSq()
-			if sq == NULL_SQ {
-				attackBBs[rank-1] = attackBB
-				continue
-			}
-			downRightProbe := sq
-			for downRightProbe.Rank() > 1 && downRightProbe.File() < 8 {
-				downRightProbe -= 7
-				mask := BBWithHighBitsAt(int(downRightProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			upLeftProbe := sq
-			for upLeftProbe.Rank() < 8 && upLeftProbe.File() > 1 {
-				upLeftProbe += 7
-				mask := BBWithHighBitsAt(int(upLeftProbe))
-				attackBB |= mask
-				if occupiedBB&mask > 0 {
-					break
-				}
-			}
-			attackBBs[rank-1] = attackBB
-		}
-		negDiagAttacks[occupiedBB] = attackBBs
-	}
+	initBetweenAndLineBBs()
 }
 
 func initPawnAttacks() {
@@ -267,60 +116,47 @@ func initKingAttacks() {
 	}
 }
 
-func genRankOccupiedBBs() []Bitboard {
-	rtn := make([]Bitboard, 0)
-	for rank := uint8(1); rank <= N_RANKS; rank++ {
-		for occupied := 1; occupied < N_ROW_PERMUS; occupied++ {
-			bb := BBWithRank(rank, uint8(occupied))
-			rtn = append(rtn, bb)
+// initBetweenAndLineBBs depends on the magics being initialized. Two squares on
+// a shared line see each other's square, and the squares they both see with only each
+// other on the board are the ones between them.
+func initBetweenAndLineBBs() {
+	for sq1 := SQ_A1; sq1 < N_SQUARES; sq1++ {
+		for _, pt := range []PieceType{BISHOP, ROOK} {
+			emptyAttacksBB := SlidingAttacksBB(BBWithSquares(sq1), sq1, pt)
+			for sq2 := SQ_A1; sq2 < N_SQUARES; sq2++ {
+				if emptyAttacksBB&BBWithSquares(sq2) == 0 {
+					continue
+				}
+				bothBB := BBWithSquares(sq1, sq2)
+				betweenBBs[sq1][sq2] = SlidingAttacksBB(bothBB, sq1, pt) & SlidingAttacksBB(bothBB, sq2, pt)
+				lineBBs[sq1][sq2] = emptyAttacksBB&SlidingAttacksBB(BBWithSquares(sq2), sq2, pt) | bothBB
+			}
 		}
 	}
-	return rtn
 }
 
-func genFileOccupiedBBs() []Bitboard {
-	rtn := make([]Bitboard, 0)
+// initPawnMasks fills the masks used to evaluate pawn structure. A pawn is passed if no
+// enemy pawn stands in its passed pawn mask: the squares in front of it on its own file
+// and on the adjacent files.
+func initPawnMasks() {
 	for file := uint8(1); file <= N_FILES; file++ {
-		for occupied := 1; occupied < N_ROW_PERMUS; occupied++ {
-			bb := BBWithFile(file, uint8(occupied))
-			rtn = append(rtn, bb)
-		}
-	}
-	return rtn
-}
-
-func genPosDiagOccupiedBBs() []Bitboard {
-	rtn := make([]Bitboard, 0)
-	for posDiagIdx := uint8(0); posDiagIdx < N_DIAGS; posDiagIdx++ {
-		var diagSize uint8
-		if posDiagIdx <= 7 {
-			diagSize = 8 - (7 - posDiagIdx)
-		} else {
-			diagSize = 8 - (posDiagIdx - 7)
+		var bb Bitboard
+		if file > 1 {
+			bb |= BBWithFile(file-1, 0b11111111)
 		}
-		nDiagPerms := 1 << diagSize
-		for occupied := 1; occupied < nDiagPerms; occupied++ {
-			bb := BBWithPosDiag(posDiagIdx, uint8(occupied))
-			rtn = append(rtn, bb)
+		if file < N_FILES {
+			bb |= BBWithFile(file+1, 0b11111111)
 		}
+		adjacentFilesBBs[file-1] = bb
 	}
-	return rtn
-}
-
-func genNegDiagOccupiedBBs() []Bitboard {
-	rtn := make([]Bitboard, 0)
-	for negDiagIdx := uint8(0); negDiagIdx < N_DIAGS; negDiagIdx++ {
-		var diagSize uint8
-		if negDiagIdx <= 7 {
-			diagSize = 8 - (7 - negDiagIdx)
-		} else {
-			diagSize = 8 - (negDiagIdx - 7)
-		}
-		nDiagPerms := 1 << diagSize
-		for occupied := 1; occupied < nDiagPerms; occupied++ {
-			bb := BBWithNegDiag(negDiagIdx, uint8(occupied))
-			rtn = append(rtn, bb)
-		}
+	for sq := SQ_A1; sq < N_SQUARES; sq++ {
+		fileBB := BBWithFile(sq.File(), 0b11111111)
+		aboveBB := ^Bitboard(0) << (8 * sq.Rank())
+		belowBB := ^Bitboard(0) >> (8 * (N_RANKS + 1 - sq.Rank()))
+		forwardFileBBs[WHITE][sq] = fileBB & aboveBB
+		forwardFileBBs[BLACK][sq] = fileBB & belowBB
+		spanBB := fileBB | adjacentFilesBBs[sq.File()-1]
+		passedPawnMasks[WHITE][sq] = spanBB & aboveBB
+		passedPawnMasks[BLACK][sq] = spanBB & belowBB
 	}
-	return rtn
 }
@@ -95,47 +95,21 @@ var _ = Describe("Precompute", func() {
 		})
 	})
 
-	Describe("#genRankOccupiedBBs", func() {
-		It("generates the appropriate number of bitboards", func() {
-			bbs := genRankOccupiedBBs()
-			Expect(bbs).To(HaveLen(8 * 255))
+	Describe("#BetweenBB", func() {
+		It("returns the squares strictly between aligned squares", func() {
+			Expect(BetweenBB(SQ_B2, SQ_E5)).To(Equal(BBWithSquares(SQ_C3, SQ_D4)))
+			Expect(BetweenBB(SQ_H8, SQ_H5)).To(Equal(BBWithSquares(SQ_H7, SQ_H6)))
+			Expect(BetweenBB(SQ_A1, SQ_B1)).To(Equal(Bitboard(0)))
 		})
-	})
-	Describe("#genFileOccupiedBBs", func() {
-		It("generates the appropriate number of bitboards", func() {
-			bbs := genFileOccupiedBBs()
-			Expect(bbs).To(HaveLen(8 * 255))
-		})
-	})
-	Describe("#genPosDiagOccupiedBBs", func() {
-		It("generates the appropriate number of bitboards", func() {
-			bbs := genPosDiagOccupiedBBs()
-			var expNBBs int
-			expNBBs += 2 * ((1 << 1) - 1) // corners
-			expNBBs += 2 * ((1 << 2) - 1) // diags 1 & 13
-			expNBBs += 2 * ((1 << 3) - 1) // diags 2 & 12
-			expNBBs += 2 * ((1 << 4) - 1) // diags 3 & 11
-			expNBBs += 2 * ((1 << 5) - 1) // diags 4 & 10
-			expNBBs += 2 * ((1 << 6) - 1) // diags 5 & 9
-			expNBBs += 2 * ((1 << 7) - 1) // diags 6 & 8
-			expNBBs += (1 << 8) - 1       // diag 7
-			Expect(bbs).To(HaveLen(expNBBs))
+		It("returns an empty bitboard for squares that aren't aligned", func() {
+			Expect(BetweenBB(SQ_A1, SQ_B3)).To(Equal(Bitboard(0)))
 		})
 	})
-	Describe("#genNegDiagOccupiedBBs", func() {
-		It("generates the appropriate number of bitboards", func() {
-			bbs := genNegDiagOccupiedBBs()
-			var expNBBs int
-			expNBBs += 2 * ((1 << 1) - 1) // corners
-			expNBBs += 2 * ((1 << 2) - 1) // diags 1 & 13
-			expNBBs += 2 * ((1 << 3) - 1) // diags 2 & 12
-			expNBBs += 2 * ((1 << 4) - 1) // diags 3 & 11
-			expNBBs += 2 * ((1 << 5) - 1) // diags 4 & 10
-			expNBBs += 2 * ((1 << 6) - 1) // diags 5 & 9
-			expNBBs += 2 * ((1 << 7) - 1) // diags 6 & 8
-			expNBBs += (1 << 8) - 1       // diag 7
-			Expect(bbs).To(HaveLen(expNBBs))
+	Describe("#LineBB", func() {
+		It("returns the whole line through aligned squares", func() {
+			Expect(LineBB(SQ_C3, SQ_D4)).To(Equal(BBWithSquares(SQ_A1, SQ_B2, SQ_C3, SQ_D4, SQ_E5, SQ_F6, SQ_G7, SQ_H8)))
+			Expect(LineBB(SQ_H3, SQ_A3)).To(Equal(BBWithRank(3, 0b11111111)))
+			Expect(LineBB(SQ_A1, SQ_B3)).To(Equal(Bitboard(0)))
 		})
 	})
-
 })
@@ -0,0 +1,72 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TaperedScore", func() {
+	Describe("::Taper", func() {
+		It("uses the middlegame score at the max phase and the endgame score at phase 0", func() {
+			score := TaperedScore{mg: 40, eg: -20}
+			Expect(score.Taper(MAX_PHASE)).To(Equal(int16(40)))
+			Expect(score.Taper(0)).To(Equal(int16(-20)))
+			Expect(score.Taper(MAX_PHASE / 2)).To(Equal(int16(10)))
+		})
+	})
+})
+
+var _ = Describe("PSTScore", func() {
+	It("is zero for the symmetric starting position", func() {
+		Expect(InitPos().pst).To(Equal(PSTScore{}))
+	})
+	It("stays equal to a full recount as moves are made and unmade", func() {
+		pos, _ := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		var walk func(depth int)
+		walk = func(depth int) {
+			Expect(pos.pst).To(Equal(NewPSTScore(pos)))
+			if depth == 0 {
+				return
+			}
+			for _, move := range GenLegalMoves(pos) {
+				captured, lastFrozenPos := pos.MakeMove(move)
+				walk(depth - 1)
+				pos.UnmakeMove(move, lastFrozenPos, captured)
+			}
+		}
+		walk(2)
+	})
+	It("covers promotions and en passant captures", func() {
+		for _, fen := range []string{
+			"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R w KQ - 0 1",
+			"rnbqkb1r/pp1p1ppp/5n2/2pPp3/8/8/PPP1PPPP/RNBQKBNR w KQkq e6 0 4",
+		} {
+			pos, _ := FromFEN(fen)
+			for _, move := range GenLegalMoves(pos) {
+				captured, lastFrozenPos := pos.MakeMove(move)
+				Expect(pos.pst).To(Equal(NewPSTScore(pos)), move.String())
+				pos.UnmakeMove(move, lastFrozenPos, captured)
+			}
+		}
+	})
+})
+
+var _ = Describe("Material", func() {
+	Describe("::Phase", func() {
+		It("is the max phase with all of the pieces on the board", func() {
+			Expect(InitPos().material.Phase()).To(Equal(MAX_PHASE))
+		})
+		It("weighs the minor pieces, rooks and queens", func() {
+			pos, _ := FromFEN("4k3/8/3b4/8/8/8/3QR3/4K3 w - - 0 1")
+			Expect(pos.material.Phase()).To(Equal(7))
+		})
+		It("is capped at the max phase after promotions", func() {
+			pos, _ := FromFEN("qqq1k3/8/8/8/8/8/8/QQQQK3 w - - 0 1")
+			Expect(pos.material.Phase()).To(Equal(MAX_PHASE))
+		})
+		It("is zero with only pawns left", func() {
+			pos, _ := FromFEN("4k3/pp6/8/8/8/8/6PP/4K3 w - - 0 1")
+			Expect(pos.material.Phase()).To(Equal(0))
+		})
+	})
+})
@@ -3,12 +3,75 @@ package main
 import (
 	"fmt"
 	"github.com/CameronHonis/marker"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const ALPHA_BETA_PRUNING_ENABLED = true
 const MOVE_SORT_ENABLED = true
 const TRANSP_TABLE_LOOKUPS_ENABLED = true
+const QUIESCENCE_ENABLED = true
+const DELTA_PRUNING_ENABLED = true
+const SEE_PRUNING_ENABLED = true
+const PVS_ENABLED = true
+const ASPIRATION_WINDOWS_ENABLED = true
+const NULL_MOVE_PRUNING_ENABLED = true
+const LMR_ENABLED = true
+const LATE_MOVE_PRUNING_ENABLED = true
+
+// DELTA_MARGIN is the slack given to a capture in quiescence search before it
+// is considered unable to raise alpha, even after winning the captured material.
+const DELTA_MARGIN = int16(200)
+
+// ASPIRATION_WINDOW is the initial distance of the root window bounds from the previous
+// iteration's score. The window doubles on each side that fails.
+const ASPIRATION_WINDOW = int16(25)
+
+// ASPIRATION_MIN_DEPTH is the shallowest depth searched with an aspiration window, as
+// scores from shallower iterations are too unstable to center a window on.
+const ASPIRATION_MIN_DEPTH = uint8(4)
+
+// NULL_MOVE_REDUCTION is how many plies shallower than a normal move the null move is
+// searched, on top of the ply the null move itself takes up
+const NULL_MOVE_REDUCTION = uint8(2)
+
+// NULL_MOVE_MIN_DEPTH is the shallowest depth the null move is tried at, so that the
+// reduced search doesn't drop straight into quiescence
+const NULL_MOVE_MIN_DEPTH = NULL_MOVE_REDUCTION + 1
+
+// LMR_MIN_DEPTH and LMR_MIN_MOVE_NUM are the shallowest depth and the earliest move, counting
+// from one, that late move reductions apply to
+const LMR_MIN_DEPTH = uint8(3)
+const LMR_MIN_MOVE_NUM = 4
+
+// LMR_BASE and LMR_DIVISOR shape the reduction of a late move, which grows with the log
+// of both the depth and the move number:
+//
+//	reduction = LMR_BASE + ln(depth) * ln(moveNum) / LMR_DIVISOR
+const LMR_BASE = 0.75
+const LMR_DIVISOR = 2.25
+
+const DEFAULT_LMR_STRENGTH = 100
+const MAX_LMR_STRENGTH = 300
+
+// LMP_MAX_DEPTH is the deepest depth that late move pruning applies to. At depth d, quiet
+// moves after the first LMP_BASE_MOVE_CNT + d*d are skipped entirely.
+const LMP_MAX_DEPTH = uint8(3)
+const LMP_BASE_MOVE_CNT = 3
+
+const lmrTableSize = 64
+
+// lmrTable holds the unscaled reduction for each depth and move number
+var lmrTable = func() (table [lmrTableSize][lmrTableSize]float64) {
+	for depth := 1; depth < lmrTableSize; depth++ {
+		for moveNum := 1; moveNum < lmrTableSize; moveNum++ {
+			table[depth][moveNum] = LMR_BASE + math.Log(float64(depth))*math.Log(float64(moveNum))/LMR_DIVISOR
+		}
+	}
+	return
+}()
 
 const PRINT_LINE = true
 const PRINT_HASH_HITS = true
@@ -21,48 +84,139 @@ type Search struct {
 	Root        *Position
 	TT          *TranspTable
 	Constraints *SearchConstraints
+	rootPly     Ply
+	maxSearchMs int
 
 	__controls__ marker.Marker
-	isHalted     bool
+	isHalted     atomic.Bool
+	isStopped    atomic.Bool
+	isPondering  atomic.Bool
+	signal       chan struct{}
+	clock        *time.Timer
+	isClockDone  bool
+	clockMu      sync.Mutex
+
+	__threads__ marker.Marker
+	helpers     []*Search
+	helperWg    sync.WaitGroup
+	lastResult  atomic.Pointer[searchResult]
 
 	__ephemeral__    marker.Marker
-	nodeCntOnDepth   int
 	hashHitsOnDepth  int
+	nullCutsOnDepth  int
+	lmrCntOnDepth    int
 	pruneCntsOnDepth []int
 
-	__accumulated__ marker.Marker
-	depth           uint8
-	score           float64
-	accNodeCnt      int
+	__accumulated__   marker.Marker
+	depth             uint8
+	rootBestMove      Move
+	excludedRootMoves []Move
+	pvScores          []int16
+	isAfterNullMove   bool
+	prevMoves         [MAX_SEARCH_PLY]Move
+	moveOrder         MoveOrderTables
+	pawnTable         *PawnTable
+	score             float64
+	accNodeCnt        atomic.Int64
+	accQNodeCnt       atomic.Int64
 }
 
 func NewSearch(pos *Position, constraints *SearchConstraints, tt *TranspTable) *Search {
-	return &Search{
+	s := &Search{
 		Root:             pos,
 		TT:               tt,
 		Constraints:      constraints,
-		isHalted:         true,
+		rootPly:          pos.ply,
+		signal:           make(chan struct{}, 1),
 		pruneCntsOnDepth: make([]int, 0),
+		pawnTable:        NewPawnTable(),
+	}
+	s.maxSearchMs = s.MaxSearchMs()
+	s.isPondering.Store(constraints.ponder)
+	return s
+}
+
+// Stop halts the search from any goroutine. The search finishes by reporting the
+// best move from the last completed iteration.
+func (s *Search) Stop() {
+	s.isStopped.Store(true)
+	s.halt()
+	s.notify()
+}
+
+// PonderHit converts a ponder search into a normal search, starting the clock
+// from the time of the call.
+func (s *Search) PonderHit() {
+	if !s.isPondering.Swap(false) {
+		return
+	}
+	if !s.Constraints.infinite {
+		s.startClock()
 	}
+	s.notify()
+}
+
+func (s *Search) IsPondering() bool {
+	return s.isPondering.Load()
+}
+
+// halt unwinds the search without ending it, as infinite and ponder searches
+// must wait for the GUI before reporting a best move.
+func (s *Search) halt() {
+	s.isHalted.Store(true)
+}
+
+func (s *Search) IsHalted() bool {
+	return s.isHalted.Load()
 }
 
 func (s *Search) IncrNode() {
-	s.accNodeCnt++
-	s.nodeCntOnDepth++
-	if s.accNodeCnt >= s.Constraints.NodeCntLmt() {
+	nodeCnt := s.accNodeCnt.Add(1)
+	// summing the helpers' node counts is too slow to do on every node
+	if len(s.helpers) > 0 && nodeCnt%1024 != 0 {
+		return
+	}
+	if s.TotalNodeCnt() >= s.Constraints.NodeCntLmt() {
 		fmt.Println("halting search, max node count reached")
-		s.isHalted = true
+		s.halt()
 	}
 }
 
+// TotalNodeCnt sums the nodes visited by this search and all of its helpers
+func (s *Search) TotalNodeCnt() int {
+	nodeCnt := s.accNodeCnt.Load()
+	for _, helper := range s.helpers {
+		nodeCnt += helper.accNodeCnt.Load()
+	}
+	return int(nodeCnt)
+}
+
+// IncrQNode is IncrNode for nodes visited by the quiescence search. These nodes
+// count towards the node limit, but are also tallied separately.
+func (s *Search) IncrQNode() {
+	s.accQNodeCnt.Add(1)
+	s.IncrNode()
+}
+
+// TotalQNodeCnt sums the quiescence nodes visited by this search and all of its helpers,
+// so that it can be compared with TotalNodeCnt
+func (s *Search) TotalQNodeCnt() int {
+	qNodeCnt := s.accQNodeCnt.Load()
+	for _, helper := range s.helpers {
+		qNodeCnt += helper.accQNodeCnt.Load()
+	}
+	return int(qNodeCnt)
+}
+
 func (s *Search) ToNextDepth() {
 	s.depth++
 	s.pruneCntsOnDepth = make([]int, s.depth)
-	s.nodeCntOnDepth = 0
 	s.hashHitsOnDepth = 0
+	s.nullCutsOnDepth = 0
+	s.lmrCntOnDepth = 0
 	if s.depth > s.Constraints.DepthLmt() {
 		fmt.Println("halting search, max depth reached")
-		s.isHalted = true
+		s.halt()
 	}
 }
 
@@ -71,104 +225,240 @@ func (s *Search) TallyPrune(parDepth, cnt int) {
 	s.pruneCntsOnDepth[idx] += cnt
 }
 
+// PV is a principal variation found for a single root move, along with its score
+type PV struct {
+	Score int16
+	Line  []Move
+}
+
 func (s *Search) Start() {
-	s.isHalted = false
-	maxSearchMs := s.MaxSearchMs()
-	go func() {
-		time.Sleep(time.Duration(maxSearchMs) * time.Millisecond)
-		fmt.Println("halting search, search time allowance reached")
-		s.isHalted = true
-	}()
+	s.TT.NextGen()
+	if !s.Constraints.infinite && !s.IsPondering() {
+		s.startClock()
+	}
+	defer s.stopClock()
+	s.startHelpers()
 	var lastResultTime = time.Now()
 	var line []Move
 	for {
 		s.ToNextDepth()
 
-		if s.isHalted {
+		if s.IsHalted() {
 			break
 		}
 
-		score, _line, halted := s.searchToDepth(s.Root, s.depth)
-		if halted {
+		pvs, halted := s.searchPVsToDepth(s.Root, s.depth)
+		if halted || len(pvs) == 0 {
 			break
 		}
-		line = _line
+		line = pvs[0].Line
+		s.lastResult.Store(&searchResult{depth: s.depth, line: line})
 		dt := time.Now().Sub(lastResultTime)
 		lastResultTime = time.Now()
-		var out = fmt.Sprintf("info depth %d score ", s.depth)
-		if score == -MATE_VAL || score == MATE_VAL {
-			out += fmt.Sprintf("mate %d", s.depth)
-		} else {
-			out += fmt.Sprintf("%d", score)
+		for pvIdx, pv := range pvs {
+			s.printInfo(pvIdx, pv, dt)
 		}
+		s.printStats()
 
-		if PRINT_LINE {
-			var lineStr string
-			for moveIdx, move := range line {
-				if moveIdx == 0 {
-					lineStr += move.String()
-				} else {
-					lineStr += " " + move.String()
-				}
-			}
-			out += fmt.Sprintf(" moves %s", lineStr)
-		} else {
-			out += fmt.Sprintf(" move %s", line[0].String())
+		// a mate within the searched depth can't be improved upon by searching deeper
+		score := pvs[0].Score
+		if IsMateScore(score) && MatePlies(score) <= int(s.depth) {
+			break
 		}
+	}
 
-		if PRINT_NODE_CNT {
-			out += fmt.Sprintf(" nodes %d", s.nodeCntOnDepth)
-		}
+	s.stopHelpers()
 
-		if PRINT_HASH_HITS {
-			out += fmt.Sprintf(" hits %d", s.hashHitsOnDepth)
+	// infinite and ponder searches may not report a best move until the GUI asks for it
+	for !s.isStopped.Load() && (s.Constraints.infinite || s.IsPondering()) {
+		<-s.signal
+	}
+
+	if s.Constraints.MultiPV() == 1 {
+		line = s.deepestLine(line)
+	}
+
+	if len(line) > 1 {
+		fmt.Printf("bestmove %s ponder %s\n", line[0].String(), line[1].String())
+	} else if len(line) > 0 {
+		fmt.Printf("bestmove %s\n", line[0].String())
+	} else {
+		// halted before the first iteration completed, any allowed legal move will do
+		iter := NewLegalMoveIter(s.Root)
+		iter.moves = s.Constraints.FilterRootMoves(iter.moves)
+		if move, done := iter.Next(); !done {
+			fmt.Printf("bestmove %s\n", move.String())
 		}
+	}
+
+}
+
+// printInfo prints the UCI info line of a PV. GUIs read everything after "pv" as moves,
+// so the line comes last.
+func (s *Search) printInfo(pvIdx int, pv PV, dt time.Duration) {
+	var out = "info"
+	if s.Constraints.MultiPV() > 1 {
+		out += fmt.Sprintf(" multipv %d", pvIdx+1)
+	}
+	out += fmt.Sprintf(" depth %d score ", s.depth)
+	if IsMateScore(pv.Score) {
+		out += fmt.Sprintf("mate %d", MateMoves(pv.Score))
+	} else {
+		out += fmt.Sprintf("cp %d", pv.Score)
+	}
+
+	if PRINT_NODE_CNT {
+		out += fmt.Sprintf(" nodes %d", s.TotalNodeCnt())
+	}
+
+	if PRINT_TIME {
+		out += fmt.Sprintf(" time %d", dt.Milliseconds())
+	}
 
-		if PRINT_PRUNE_CNTS {
-			out += " pruned"
-			for _, pruneCnt := range s.pruneCntsOnDepth {
-				out += fmt.Sprintf(" %d", pruneCnt)
+	if PRINT_LINE {
+		var lineStr string
+		for moveIdx, move := range pv.Line {
+			if moveIdx == 0 {
+				lineStr += move.String()
+			} else {
+				lineStr += " " + move.String()
 			}
 		}
+		out += fmt.Sprintf(" pv %s", lineStr)
+	} else {
+		out += fmt.Sprintf(" pv %s", pv.Line[0].String())
+	}
+	fmt.Println(out)
+}
 
-		if PRINT_TIME {
-			out += fmt.Sprintf(" time %dms", dt.Milliseconds())
-		}
-		fmt.Println(out)
+// printStats prints the search counters. The quiescence node count covers the whole search,
+// like the node count of the info line, while the rest only cover the last depth searched.
+// They aren't part of UCI, so they go on an "info string" line, which GUIs show as is.
+func (s *Search) printStats() {
+	var out string
+	if PRINT_NODE_CNT && QUIESCENCE_ENABLED {
+		out += fmt.Sprintf(" qnodes %d", s.TotalQNodeCnt())
+	}
 
-		if score == -MATE_VAL || score == MATE_VAL {
-			break
+	if PRINT_HASH_HITS {
+		out += fmt.Sprintf(" hits %d", s.hashHitsOnDepth)
+	}
+
+	if PRINT_PRUNE_CNTS {
+		out += " pruned"
+		for _, pruneCnt := range s.pruneCntsOnDepth {
+			out += fmt.Sprintf(" %d", pruneCnt)
+		}
+		if NULL_MOVE_PRUNING_ENABLED {
+			out += fmt.Sprintf(" nullcuts %d", s.nullCutsOnDepth)
+		}
+		if LMR_ENABLED {
+			out += fmt.Sprintf(" reduced %d", s.lmrCntOnDepth)
 		}
 	}
-	if len(line) > 0 {
-		fmt.Printf("bestmove %s\n", line[0].String())
+	if out != "" {
+		fmt.Println("info string" + out)
 	}
+}
 
+// searchPVsToDepth searches the best MultiPV root moves, best first. Each PV is found
+// by searching the root again with the root moves of the previous PVs excluded.
+func (s *Search) searchPVsToDepth(pos *Position, depth uint8) (pvs []PV, halted bool) {
+	defer func() {
+		s.excludedRootMoves = s.excludedRootMoves[:0]
+	}()
+	pvs = make([]PV, 0, s.Constraints.MultiPV())
+	for len(pvs) < s.Constraints.MultiPV() {
+		var score int16
+		var line []Move
+		score, line, halted = s.searchToDepth(pos, depth)
+		if halted {
+			return nil, halted
+		}
+		if len(line) == 0 {
+			break
+		}
+		pvs = append(pvs, PV{Score: score, Line: line})
+		s.excludedRootMoves = append(s.excludedRootMoves, line[0])
+	}
+	return pvs, false
 }
 
+// searchToDepth searches the root with a window around this PV's score from the previous
+// iteration, widening the window on a fail high or low until the score falls within it.
 func (s *Search) searchToDepth(pos *Position, depth uint8) (score int16, line []Move, halted bool) {
 	if !pos.HasLegalMoves() {
 		if pos.IsMate() {
 			return -MATE_VAL, make([]Move, 0), false
 		} else {
-			return DRAW_VAL, make([]Move, 0), false
+			return evalParams.DrawVal, make([]Move, 0), false
+		}
+	}
+	pvIdx := len(s.excludedRootMoves)
+	alpha, beta := -MATE_VAL, MATE_VAL
+	// the bounds are widened in int, as doubling the window soon overflows an int16
+	var window = int(ASPIRATION_WINDOW)
+	var isAspirating = ASPIRATION_WINDOWS_ENABLED && depth >= ASPIRATION_MIN_DEPTH && pvIdx < len(s.pvScores) &&
+		!IsMateScore(s.pvScores[pvIdx])
+	if isAspirating {
+		alpha = int16(MaxInt(int(s.pvScores[pvIdx])-window, int(-MATE_VAL)))
+		beta = int16(MinInt(int(s.pvScores[pvIdx])+window, int(MATE_VAL)))
+	}
+	for {
+		s.rootBestMove = NULL_MOVE
+		score, halted = s._searchToDepth(pos, depth, alpha, beta)
+		if halted {
+			return score, make([]Move, 0), halted
+		}
+		if score <= alpha && alpha > -MATE_VAL {
+			window *= 2
+			alpha = int16(MaxInt(int(score)-window, int(-MATE_VAL)))
+		} else if score >= beta && beta < MATE_VAL {
+			window *= 2
+			beta = int16(MinInt(int(score)+window, int(MATE_VAL)))
+		} else {
+			break
 		}
 	}
-	score, halted = s._searchToDepth(pos, depth, -MATE_VAL, MATE_VAL)
-	line = s.TT.Line(pos, depth)
+
+	if pvIdx < len(s.pvScores) {
+		s.pvScores[pvIdx] = score
+	} else {
+		s.pvScores = append(s.pvScores, score)
+	}
+	line = s.rootLine(pos, depth)
 	return
 }
 
+// rootLine builds the principal variation from the root move chosen by the last
+// search, rather than the root's TT entry, which may be left over from a deeper
+// search over a different set of root moves.
+func (s *Search) rootLine(pos *Position, depth uint8) []Move {
+	if s.rootBestMove == NULL_MOVE {
+		return make([]Move, 0)
+	}
+	captPiece, lastFrozenPos := pos.MakeMove(s.rootBestMove)
+	line := append([]Move{s.rootBestMove}, s.TT.Line(pos, depth-1)...)
+	pos.UnmakeMove(s.rootBestMove, lastFrozenPos, captPiece)
+	return line
+}
+
 func (s *Search) _searchToDepth(pos *Position, depth uint8, alpha int16, beta int16) (score int16, halted bool) {
-	if s.isHalted {
+	if s.IsHalted() {
 		return alpha, true
 	}
+	ply := s.plyFromRoot(pos)
+	isAfterNullMove := s.isAfterNullMove
+	s.isAfterNullMove = false
 	if depth == 0 || pos.result != RESULT_IN_PROGRESS {
+		if QUIESCENCE_ENABLED && pos.result == RESULT_IN_PROGRESS {
+			return s.quiesce(pos, alpha, beta)
+		}
 		if pos.IsMate() {
-			return -MATE_VAL, false
+			return MatedIn(ply), false
 		}
 		s.IncrNode()
-		return EvalPos(pos), false
+		return EvalPosCached(pos, s.pawnTable), false
 	}
 
 	var anticipated = NULL_MOVE
@@ -176,39 +466,95 @@ func (s *Search) _searchToDepth(pos *Position, depth uint8, alpha int16, beta in
 		entry, exists := s.TT.GetEntry(pos.hash)
 		if exists {
 			s.hashHitsOnDepth++
-			if entry.Depth >= depth {
-				if entry.IsExact() {
-					return entry.Score, false
-				} else { // Is Lower bound score
-					if entry.Score >= beta {
-						return entry.Score, false
-					} else {
-						anticipated = entry.Move
-					}
-				}
-			} else { // estimate isn't deep enough
-				anticipated = entry.Move
+			entry.Score = ScoreFromTT(entry.Score, ply)
+			// the root entry may come from a search over a different set of root moves
+			if ply > 0 && entry.Depth >= depth && entry.IsCutoff(alpha, beta) {
+				return entry.Score, false
 			}
+			// estimate isn't deep enough or its bound doesn't settle this window
+			anticipated = entry.Move
 		}
 	}
 
-	iter := NewLegalMoveIter(pos)
-	if MOVE_SORT_ENABLED {
-		iter.pMoves = SortMoves(pos, iter.pMoves, anticipated)
+	if NULL_MOVE_PRUNING_ENABLED && ply > 0 && !isAfterNullMove && s.isNullMoveAllowed(pos, depth, beta) {
+		lastFrozenPos := pos.MakeNullMove()
+		s.isAfterNullMove = true
+		s.prevMoves[ply] = NULL_MOVE
+		var nullScore int16
+		nullScore, halted = s._searchToDepth(pos, depth-1-NULL_MOVE_REDUCTION, -beta, -beta+1)
+		nullScore = -nullScore
+		s.isAfterNullMove = false
+		pos.UnmakeNullMove(lastFrozenPos)
+		if halted {
+			return 0, halted
+		}
+		if nullScore >= beta {
+			s.nullCutsOnDepth++
+			// a mate found after passing the turn isn't proven, as passing isn't legal
+			if IsMateScore(nullScore) {
+				return beta, false
+			}
+			return nullScore, false
+		}
 	}
 
+	iter := NewStagedMoveIter(pos, &s.moveOrder, anticipated, ply, s.prevMove(ply))
+	if ply == 0 {
+		iter.Restrict(s.isRootMoveAllowed)
+	}
+
+	origAlpha := alpha
+	isChecked := pos.IsKingChecked()
 	score = -MATE_VAL - 1
 	var bestMove Move
+	var moveNum = 0
+	var failedQuiets []Move
 	for {
 		move, done := iter.Next()
 		if done {
 			break
 		}
+		moveNum++
 
+		isQuiet := !pos.IsCapture(move) && move.Type() != PAWN_PROMOTION
+		isReducible := isQuiet && move != anticipated && !s.moveOrder.IsKiller(ply, move)
 		captPiece, lastFrozenPos := pos.MakeMove(move)
+		s.prevMoves[ply] = move
+		isLateQuietMove := moveNum > 1 && isReducible && !isChecked && !pos.IsKingChecked()
+		// every root move is searched, so that shallow searches and MultiPV see them all
+		if LATE_MOVE_PRUNING_ENABLED && isLateQuietMove && ply > 0 && s.isLateMovePrunable(depth, moveNum, score) {
+			pos.UnmakeMove(move, lastFrozenPos, captPiece)
+			s.TallyPrune(int(depth), 1)
+			continue
+		}
+
 		var moveScore int16
-		moveScore, halted = s._searchToDepth(pos, depth-1, -beta, -alpha)
-		moveScore = -moveScore
+		if !PVS_ENABLED || moveNum == 1 {
+			moveScore, halted = s._searchToDepth(pos, depth-1, -beta, -alpha)
+			moveScore = -moveScore
+		} else {
+			// with good move ordering the first move is the best, so the rest only need
+			// to be proven worse with a null window, and are re-searched if they aren't.
+			// Late quiet moves are even less likely to be best, so they are also searched
+			// shallower, and only re-searched at full depth if they beat alpha.
+			var reduction uint8
+			if LMR_ENABLED && isLateQuietMove && ply > 0 {
+				reduction = s.lateMoveReduction(depth, moveNum)
+			}
+			moveScore, halted = s._searchToDepth(pos, depth-1-reduction, -alpha-1, -alpha)
+			moveScore = -moveScore
+			if reduction > 0 {
+				s.lmrCntOnDepth++
+				if !halted && moveScore > alpha {
+					moveScore, halted = s._searchToDepth(pos, depth-1, -alpha-1, -alpha)
+					moveScore = -moveScore
+				}
+			}
+			if !halted && moveScore > alpha && moveScore < beta {
+				moveScore, halted = s._searchToDepth(pos, depth-1, -beta, -alpha)
+				moveScore = -moveScore
+			}
+		}
 		pos.UnmakeMove(move, lastFrozenPos, captPiece)
 		if halted {
 			return 0, halted
@@ -223,20 +569,190 @@ func (s *Search) _searchToDepth(pos *Position, depth uint8, alpha int16, beta in
 		}
 
 		if ALPHA_BETA_PRUNING_ENABLED && moveScore >= beta {
-			s.TallyPrune(int(depth), len(iter.pMoves)-1-iter.idx)
+			s.TallyPrune(int(depth), iter.Remaining())
+			if isQuiet {
+				s.moveOrder.RecordCutoff(pos, ply, depth, s.prevMove(ply), move, failedQuiets)
+			}
 			break
 		}
+		if isQuiet {
+			failedQuiets = append(failedQuiets, move)
+		}
 	}
 
-	if alpha < beta {
-		s.TT.PostResults(pos.hash, score, false, bestMove, depth)
+	if bestMove == NULL_MOVE {
+		if isChecked {
+			score = MatedIn(ply)
+		} else {
+			score = evalParams.DrawVal
+		}
+	}
+
+	if ply == 0 {
+		s.rootBestMove = bestMove
+	}
+	s.TT.PostResults(pos.hash, ScoreToTT(score, ply), NewTTEntryType(score, origAlpha, beta), bestMove, depth)
+
+	return
+}
+
+// quiesce extends the search past the depth horizon by only considering captures and
+// promotions until the position is quiet. The side to move may always "stand pat" on
+// the static eval, unless it is in check, in which case every evasion is searched.
+func (s *Search) quiesce(pos *Position, alpha int16, beta int16) (score int16, halted bool) {
+	if s.IsHalted() {
+		return alpha, true
+	}
+	s.IncrQNode()
+	if pos.result != RESULT_IN_PROGRESS {
+		return EvalPosCached(pos, s.pawnTable), false
+	}
+
+	isChecked := pos.IsKingChecked()
+	var standPat int16
+	var iter *StagedMoveIter
+	if isChecked {
+		score = MatedIn(s.plyFromRoot(pos))
+		iter = NewStagedMoveIter(pos, &s.moveOrder, NULL_MOVE, s.plyFromRoot(pos), NULL_MOVE)
 	} else {
-		s.TT.PostResults(pos.hash, score, true, bestMove, depth)
+		standPat = EvalPosCached(pos, s.pawnTable)
+		if ALPHA_BETA_PRUNING_ENABLED && standPat >= beta {
+			return standPat, false
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		score = standPat
+		// standing pat is at least as good as a capture that loses material
+		iter = NewStagedCaptureIter(pos, &s.moveOrder, SEE_PRUNING_ENABLED)
 	}
 
+	for {
+		move, done := iter.Next()
+		if done {
+			break
+		}
+
+		if DELTA_PRUNING_ENABLED && !isChecked && standPat+EvalMove(pos, move)+DELTA_MARGIN <= alpha {
+			continue
+		}
+
+		captPiece, lastFrozenPos := pos.MakeMove(move)
+		var moveScore int16
+		moveScore, halted = s.quiesce(pos, -beta, -alpha)
+		moveScore = -moveScore
+		pos.UnmakeMove(move, lastFrozenPos, captPiece)
+		if halted {
+			return 0, halted
+		}
+
+		if moveScore > score {
+			score = moveScore
+			if moveScore > alpha {
+				alpha = moveScore
+			}
+		}
+
+		if ALPHA_BETA_PRUNING_ENABLED && moveScore >= beta {
+			break
+		}
+	}
 	return
 }
 
+// isNullMoveAllowed returns true if passing the turn is a safe test of whether the node
+// fails high. Passing is illegal in check, and in zugzwang-prone positions passing is
+// often the best move, which would falsely prove the node fails high.
+func (s *Search) isNullMoveAllowed(pos *Position, depth uint8, beta int16) bool {
+	if depth < NULL_MOVE_MIN_DEPTH || IsMateScore(beta) {
+		return false
+	}
+	if !pos.material.HasPieces(NewColor(pos.isWhiteTurn)) {
+		return false
+	}
+	if pos.IsKingChecked() {
+		return false
+	}
+	return EvalPosCached(pos, s.pawnTable) >= beta
+}
+
+// lateMoveReduction returns how many plies shallower a late quiet move is searched, scaled
+// by the LMR strength. The reduced search never drops below depth one.
+func (s *Search) lateMoveReduction(depth uint8, moveNum int) uint8 {
+	if depth < LMR_MIN_DEPTH || moveNum < LMR_MIN_MOVE_NUM {
+		return 0
+	}
+	baseReduction := lmrTable[MinInt(int(depth), lmrTableSize-1)][MinInt(moveNum, lmrTableSize-1)]
+	reduction := int(baseReduction * float64(s.Constraints.LMRStrength()) / 100)
+	return uint8(MaxInt(0, MinInt(reduction, int(depth)-2)))
+}
+
+// isLateMovePrunable returns true if a late quiet move is so far down the move order at a
+// shallow depth that it isn't worth searching at all. Moves are never pruned while every
+// move searched so far loses to a mate, as a quiet move may be the only defense.
+func (s *Search) isLateMovePrunable(depth uint8, moveNum int, bestScore int16) bool {
+	if depth > LMP_MAX_DEPTH || bestScore <= -MATE_VAL+MAX_MATE_PLY {
+		return false
+	}
+	return moveNum > LMP_BASE_MOVE_CNT+int(depth)*int(depth)
+}
+
+// isRootMoveAllowed returns false for root moves excluded by searchmoves or already
+// searched as an earlier MultiPV line
+func (s *Search) isRootMoveAllowed(move Move) bool {
+	if !s.Constraints.AllowsRootMove(move) {
+		return false
+	}
+	for _, excludedMove := range s.excludedRootMoves {
+		if move == excludedMove {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Search) notify() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Search) startClock() {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+	if s.clock != nil || s.isClockDone {
+		return
+	}
+	// the root is mutated while searching, so the allowance is computed up front
+	s.clock = time.AfterFunc(time.Duration(s.maxSearchMs)*time.Millisecond, func() {
+		fmt.Println("halting search, search time allowance reached")
+		s.halt()
+	})
+}
+
+func (s *Search) stopClock() {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+	if s.clock != nil {
+		s.clock.Stop()
+	}
+	s.isClockDone = true
+}
+
+// prevMove returns the move made to reach the node at ply, which is NULL_MOVE at the root
+// and after a null move
+func (s *Search) prevMove(ply Ply) Move {
+	if ply == 0 {
+		return NULL_MOVE
+	}
+	return s.prevMoves[ply-1]
+}
+
+func (s *Search) plyFromRoot(pos *Position) Ply {
+	return pos.ply - s.rootPly
+}
+
 func (s *Search) MaxSearchMs() int {
 	var msForSearch = func(pos *Position, bankMs int, incrMs int) int {
 		expMoves := ExpMoves(pos)
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newTestSearch(fen string, constraints *SearchConstraints) *Search {
+	pos, posErr := FromFEN(fen)
+	Expect(posErr).ToNot(HaveOccurred())
+	return NewSearch(pos, constraints, NewTranspTable(MIN_HASH_MB))
+}
+
+// captureStdout returns what f prints
+func captureStdout(f func()) string {
+	reader, writer, pipeErr := os.Pipe()
+	Expect(pipeErr).ToNot(HaveOccurred())
+	stdout := os.Stdout
+	os.Stdout = writer
+	f()
+	os.Stdout = stdout
+	Expect(writer.Close()).To(Succeed())
+	out, readErr := io.ReadAll(reader)
+	Expect(readErr).ToNot(HaveOccurred())
+	return string(out)
+}
+
+var _ = Describe("Search", func() {
+	Describe("::printInfo", func() {
+		It("ends the UCI line with the pv and leaves the counters to an info string", func() {
+			s := newTestSearch("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1", &SearchConstraints{})
+			s.ToNextDepth()
+			pv := PV{Score: 25, Line: []Move{NewNormalMove(SQ_E2, SQ_E5), NewNormalMove(SQ_D6, SQ_E5)}}
+			out := captureStdout(func() {
+				s.printInfo(0, pv, 1500*time.Millisecond)
+				s.printStats()
+			})
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[0]).To(MatchRegexp(`^info depth 1 score cp 25 nodes \d+ time 1500 pv e2e5 d6e5$`))
+			Expect(lines[1]).To(HavePrefix("info string "))
+		})
+	})
+	Describe("::quiesce", func() {
+		When("the only capture loses material", func() {
+			It("stands pat on the static eval", func() {
+				s := newTestSearch("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1", &SearchConstraints{})
+				score, halted := s.quiesce(s.Root, -MATE_VAL, MATE_VAL)
+				Expect(halted).To(BeFalse())
+				Expect(score).To(Equal(EvalPos(s.Root)))
+			})
+		})
+		When("a piece is left hanging", func() {
+			It("accounts for the capture", func() {
+				s := newTestSearch("4k3/8/8/3p4/8/8/3Q4/4K3 w - - 0 1", &SearchConstraints{})
+				score, halted := s.quiesce(s.Root, -MATE_VAL, MATE_VAL)
+				Expect(halted).To(BeFalse())
+				afterCapture := s.Root.Copy()
+				_, _ = afterCapture.MakeMove(NewNormalMove(SQ_D2, SQ_D5))
+				Expect(score).To(Equal(-EvalPos(afterCapture)))
+			})
+		})
+		It("tallies quiescence nodes separately", func() {
+			s := newTestSearch("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1", &SearchConstraints{})
+			_, _ = s.quiesce(s.Root, -MATE_VAL, MATE_VAL)
+			Expect(s.TotalQNodeCnt()).To(BeNumerically(">", 0))
+			Expect(s.TotalNodeCnt()).To(Equal(s.TotalQNodeCnt()))
+		})
+	})
+	Describe("::searchToDepth", func() {
+		It("never prunes late quiet moves at the root", func() {
+			for depth := uint8(1); depth <= 3; depth++ {
+				s := newTestSearch("8/2p4k/3p4/4Q3/8/8/PPPPP3/RN2K3 w - - 0 1", &SearchConstraints{})
+				for s.depth < depth {
+					s.ToNextDepth()
+				}
+				_, _, halted := s.searchToDepth(s.Root, depth)
+				Expect(halted).To(BeFalse())
+				Expect(s.pruneCntsOnDepth[0]).To(BeZero(), "depth %d", depth)
+			}
+		})
+		It("does not stop in the middle of an exchange at the horizon", func() {
+			s := newTestSearch("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1", &SearchConstraints{})
+			s.ToNextDepth()
+			score, _, halted := s.searchToDepth(s.Root, 1)
+			Expect(halted).To(BeFalse())
+			Expect(score).To(BeNumerically("<", QUEEN_VAL-PAWN_VAL))
+		})
+		When("the previous iteration's score is far from the true score", func() {
+			It("widens the aspiration window until the score falls within it", func() {
+				searchFrom := func(prevScore int16) (int16, []Move) {
+					s := newTestSearch("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1", &SearchConstraints{})
+					for s.depth < ASPIRATION_MIN_DEPTH {
+						s.ToNextDepth()
+					}
+					if prevScore != 0 {
+						s.pvScores = []int16{prevScore}
+					}
+					score, line, halted := s.searchToDepth(s.Root, s.depth)
+					Expect(halted).To(BeFalse())
+					Expect(s.pvScores).To(Equal([]int16{score}))
+					return score, line
+				}
+				fullWindowScore, fullWindowLine := searchFrom(0)
+				for _, prevScore := range []int16{-900, 900} {
+					score, line := searchFrom(prevScore)
+					Expect(score).To(Equal(fullWindowScore))
+					Expect(line[0]).To(Equal(fullWindowLine[0]))
+				}
+			})
+		})
+	})
+	Describe("::_searchToDepth", func() {
+		var s *Search
+		BeforeEach(func() {
+			s = newTestSearch("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1", &SearchConstraints{})
+			s.ToNextDepth()
+		})
+		When("the node fails high", func() {
+			It("stores the score as a lower bound", func() {
+				score, _ := s._searchToDepth(s.Root, 1, 0, 100)
+				Expect(score).To(BeNumerically(">=", 100))
+				entry, exists := s.TT.GetEntry(s.Root.hash)
+				Expect(exists).To(BeTrue())
+				Expect(entry.Type).To(Equal(LOWER_BOUND_ENTRY))
+				Expect(entry.Score).To(Equal(score))
+			})
+		})
+		When("the node fails low", func() {
+			It("stores the score as an upper bound", func() {
+				score, _ := s._searchToDepth(s.Root, 1, 800, 900)
+				Expect(score).To(BeNumerically("<=", 800))
+				entry, exists := s.TT.GetEntry(s.Root.hash)
+				Expect(exists).To(BeTrue())
+				Expect(entry.Type).To(Equal(UPPER_BOUND_ENTRY))
+				Expect(entry.Score).To(Equal(score))
+			})
+			It("cuts on the upper bound when probed with the same window below the root", func() {
+				_, _ = s.Root.MakeMove(NewNormalMove(SQ_E1, SQ_D1))
+				score, _ := s._searchToDepth(s.Root, 1, -500, -400)
+				Expect(score).To(BeNumerically("<=", -500))
+				nodeCnt := s.TotalNodeCnt()
+				reprobedScore, _ := s._searchToDepth(s.Root, 1, -500, -400)
+				Expect(reprobedScore).To(Equal(score))
+				Expect(s.TotalNodeCnt()).To(Equal(nodeCnt))
+			})
+		})
+		When("the score falls within the window", func() {
+			It("stores the score as exact", func() {
+				score, _ := s._searchToDepth(s.Root, 1, -MATE_VAL, MATE_VAL)
+				entry, exists := s.TT.GetEntry(s.Root.hash)
+				Expect(exists).To(BeTrue())
+				Expect(entry.Type).To(Equal(EXACT_ENTRY))
+				Expect(entry.Score).To(Equal(score))
+			})
+		})
+	})
+	Describe("::isNullMoveAllowed", func() {
+		It("allows the null move in a quiet middlegame", func() {
+			s := newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+			Expect(s.isNullMoveAllowed(s.Root, NULL_MOVE_MIN_DEPTH, EvalPos(s.Root))).To(BeTrue())
+		})
+		It("disallows the null move too close to the horizon", func() {
+			s := newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+			Expect(s.isNullMoveAllowed(s.Root, NULL_MOVE_MIN_DEPTH-1, 0)).To(BeFalse())
+		})
+		It("disallows the null move in check", func() {
+			s := newTestSearch("4k3/8/8/8/8/8/3PPP2/R2QK2r w - - 0 1", &SearchConstraints{})
+			Expect(s.isNullMoveAllowed(s.Root, NULL_MOVE_MIN_DEPTH, -MATE_VAL+MAX_MATE_PLY+1)).To(BeFalse())
+		})
+		It("disallows the null move when the side to move only has pawns", func() {
+			s := newTestSearch("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", &SearchConstraints{})
+			Expect(s.isNullMoveAllowed(s.Root, NULL_MOVE_MIN_DEPTH, -MATE_VAL+MAX_MATE_PLY+1)).To(BeFalse())
+		})
+		It("disallows the null move when the static eval is below beta", func() {
+			s := newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+			Expect(s.isNullMoveAllowed(s.Root, NULL_MOVE_MIN_DEPTH, EvalPos(s.Root)+1)).To(BeFalse())
+		})
+	})
+	Describe("null move pruning", func() {
+		It("does not pass twice in a row", func() {
+			s := newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+			_, _ = s.Root.MakeMove(NewNormalMove(SQ_F1, SQ_C4))
+			for s.depth < NULL_MOVE_MIN_DEPTH {
+				s.ToNextDepth()
+			}
+			s.isAfterNullMove = true
+			_, _ = s._searchToDepth(s.Root, NULL_MOVE_MIN_DEPTH, -1, 0)
+			Expect(s.nullCutsOnDepth).To(Equal(0))
+			Expect(s.isAfterNullMove).To(BeFalse())
+		})
+		It("never passes in a pawn endgame", func() {
+			s := newTestSearch("8/8/8/3k4/8/3K4/3P4/8 w - - 0 1", &SearchConstraints{})
+			for s.depth < 6 {
+				s.ToNextDepth()
+				_, _, _ = s.searchToDepth(s.Root, s.depth)
+				Expect(s.nullCutsOnDepth).To(Equal(0))
+			}
+		})
+	})
+	Describe("::lateMoveReduction", func() {
+		var s *Search
+		BeforeEach(func() {
+			s = newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+		})
+		It("does not reduce early moves or shallow depths", func() {
+			Expect(s.lateMoveReduction(10, LMR_MIN_MOVE_NUM-1)).To(Equal(uint8(0)))
+			Expect(s.lateMoveReduction(LMR_MIN_DEPTH-1, 30)).To(Equal(uint8(0)))
+		})
+		It("reduces later moves and deeper depths at least as much", func() {
+			Expect(s.lateMoveReduction(10, 30)).To(BeNumerically(">", 0))
+			Expect(s.lateMoveReduction(10, 30)).To(BeNumerically(">=", s.lateMoveReduction(10, 5)))
+			Expect(s.lateMoveReduction(20, 30)).To(BeNumerically(">=", s.lateMoveReduction(10, 30)))
+		})
+		It("leaves at least one ply to search", func() {
+			s.Constraints.lmrStrength = MAX_LMR_STRENGTH
+			for depth := LMR_MIN_DEPTH; depth < 20; depth++ {
+				Expect(s.lateMoveReduction(depth, 60)).To(BeNumerically("<=", depth-2))
+			}
+		})
+		It("scales with the LMR strength", func() {
+			s.Constraints.lmrStrength = 50
+			weakReduction := s.lateMoveReduction(20, 40)
+			s.Constraints.lmrStrength = 200
+			Expect(s.lateMoveReduction(20, 40)).To(BeNumerically(">", weakReduction))
+		})
+	})
+	Describe("::isLateMovePrunable", func() {
+		var s *Search
+		BeforeEach(func() {
+			s = newTestSearch("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", &SearchConstraints{})
+		})
+		It("prunes moves past the move count for the depth", func() {
+			Expect(s.isLateMovePrunable(1, LMP_BASE_MOVE_CNT+1, 0)).To(BeFalse())
+			Expect(s.isLateMovePrunable(1, LMP_BASE_MOVE_CNT+2, 0)).To(BeTrue())
+		})
+		It("does not prune deeper than LMP_MAX_DEPTH", func() {
+			Expect(s.isLateMovePrunable(LMP_MAX_DEPTH+1, 100, 0)).To(BeFalse())
+		})
+		It("does not prune while every move so far gets mated", func() {
+			Expect(s.isLateMovePrunable(1, 100, MatedIn(2))).To(BeFalse())
+		})
+	})
+	Describe("move ordering", func() {
+		It("tries a quiet cutoff move earlier on a re-search", func() {
+			// only the quiet back rank mate reaches beta
+			s := newTestSearch("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", &SearchConstraints{})
+			mate := NewNormalMove(SQ_A1, SQ_A8)
+			orderIdx := func() int {
+				iter := NewStagedMoveIter(s.Root, &s.moveOrder, NULL_MOVE, 0, NULL_MOVE)
+				for moveIdx := 0; ; moveIdx++ {
+					move, done := iter.Next()
+					if done {
+						return -1
+					}
+					if move == mate {
+						return moveIdx
+					}
+				}
+			}
+			Expect(orderIdx()).To(BeNumerically(">", 0))
+
+			s.ToNextDepth()
+			score, _ := s._searchToDepth(s.Root, 1, MATE_VAL-3, MATE_VAL-2)
+			Expect(score).To(Equal(MATE_VAL - 1))
+			Expect(s.moveOrder.IsKiller(0, mate)).To(BeTrue())
+			Expect(s.moveOrder.History(WHITE, mate)).To(BeNumerically(">", 0))
+			Expect(orderIdx()).To(Equal(0))
+		})
+	})
+	Describe("mate scores", func() {
+		searchTo := func(s *Search, depth uint8) int16 {
+			var score int16
+			for s.depth < depth {
+				s.ToNextDepth()
+				score, _, _ = s.searchToDepth(s.Root, s.depth)
+			}
+			return score
+		}
+		When("there is a mate in one", func() {
+			It("scores the mate one ply from the root", func() {
+				s := newTestSearch("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", &SearchConstraints{})
+				score := searchTo(s, 2)
+				Expect(score).To(Equal(MATE_VAL - 1))
+				Expect(MateMoves(score)).To(Equal(1))
+			})
+		})
+		When("there is a mate in two", func() {
+			It("scores the mate three plies from the root", func() {
+				s := newTestSearch("k7/8/2K5/8/8/8/8/7R w - - 0 1", &SearchConstraints{})
+				score := searchTo(s, 4)
+				Expect(score).To(Equal(MATE_VAL - 3))
+				Expect(MateMoves(score)).To(Equal(2))
+			})
+		})
+		When("the side to move is getting mated in one", func() {
+			It("scores the mate two plies from the root", func() {
+				s := newTestSearch("k7/8/1K6/8/8/8/8/7R b - - 0 1", &SearchConstraints{})
+				score := searchTo(s, 3)
+				Expect(score).To(Equal(-MATE_VAL + 2))
+				Expect(MateMoves(score)).To(Equal(-1))
+			})
+		})
+	})
+	Describe("::Stop", func() {
+		It("halts a running search", func() {
+			s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{})
+			searchDone := make(chan struct{})
+			go func() {
+				s.Start()
+				close(searchDone)
+			}()
+			Consistently(searchDone, "50ms").ShouldNot(BeClosed())
+			s.Stop()
+			Eventually(searchDone, "1s").Should(BeClosed())
+		})
+		When("the search is stopped before it starts", func() {
+			It("does not search", func() {
+				s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{})
+				s.Stop()
+				s.Start()
+				Expect(s.TotalNodeCnt()).To(Equal(0))
+			})
+		})
+	})
+	Describe("::Start", func() {
+		var searchDone chan struct{}
+		start := func(s *Search) {
+			searchDone = make(chan struct{})
+			go func() {
+				s.Start()
+				close(searchDone)
+			}()
+		}
+		When("the search is infinite", func() {
+			It("does not finish until stopped, even after reaching its depth limit", func() {
+				s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{maxDepth: 2, infinite: true})
+				start(s)
+				Consistently(searchDone, "50ms").ShouldNot(BeClosed())
+				s.Stop()
+				Eventually(searchDone, "1s").Should(BeClosed())
+			})
+		})
+		When("the search is pondering", func() {
+			It("does not finish before ponderhit", func() {
+				s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{maxDepth: 2, ponder: true})
+				start(s)
+				Consistently(searchDone, "50ms").ShouldNot(BeClosed())
+				s.PonderHit()
+				Eventually(searchDone, "1s").Should(BeClosed())
+			})
+			It("starts the clock on ponderhit", func() {
+				s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{maxMs: 50, ponder: true})
+				start(s)
+				Consistently(searchDone, "100ms").ShouldNot(BeClosed())
+				Expect(s.IsHalted()).To(BeFalse())
+				s.PonderHit()
+				Expect(s.IsPondering()).To(BeFalse())
+				Eventually(searchDone, "1s").Should(BeClosed())
+			})
+		})
+	})
+	Describe("Lazy SMP", func() {
+		It("reports a legal move and counts the helpers' nodes", func() {
+			s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{infinite: true, threads: 3})
+			rootFEN := s.Root.FEN()
+			searchDone := make(chan struct{})
+			go func() {
+				s.Start()
+				close(searchDone)
+			}()
+			Consistently(searchDone, "100ms").ShouldNot(BeClosed())
+			s.Stop()
+			Eventually(searchDone, "1s").Should(BeClosed())
+			Expect(s.Root.FEN()).To(Equal(rootFEN))
+			Expect(s.helpers).To(HaveLen(2))
+			var helperNodeCnt, helperQNodeCnt int64
+			for _, helper := range s.helpers {
+				Expect(helper.Root).ToNot(BeIdenticalTo(s.Root))
+				helperNodeCnt += helper.accNodeCnt.Load()
+				helperQNodeCnt += helper.accQNodeCnt.Load()
+			}
+			Expect(helperNodeCnt).To(BeNumerically(">", 0))
+			Expect(s.TotalNodeCnt()).To(Equal(int(s.accNodeCnt.Load() + helperNodeCnt)))
+			Expect(s.TotalQNodeCnt()).To(Equal(int(s.accQNodeCnt.Load() + helperQNodeCnt)))
+			Expect(s.TotalQNodeCnt()).To(BeNumerically("<=", s.TotalNodeCnt()))
+			line := s.deepestLine(nil)
+			Expect(line).ToNot(BeEmpty())
+			Expect(s.Root.HasLegalMove(line[0])).To(BeTrue())
+		})
+		It("keeps the helpers to the depth limit", func() {
+			s := newTestSearch("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", &SearchConstraints{maxDepth: 3, threads: 3})
+			s.startHelpers()
+			s.stopHelpers()
+			Expect(s.helpers).To(HaveLen(2))
+			for _, helper := range s.helpers {
+				Expect(helper.Constraints.DepthLmt()).To(Equal(uint8(3)))
+				// run the helper to completion, without the main search halting it
+				helper.isHalted.Store(false)
+				helper.searchIteratively()
+				Expect(helper.lastResult.Load().depth).To(Equal(uint8(3)))
+			}
+		})
+		When("searchmoves is given", func() {
+			It("restricts the helpers to the same root moves", func() {
+				allowedMoves := []Move{NewNormalMove(SQ_A2, SQ_A3)}
+				s := newTestSearch("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1", &SearchConstraints{maxDepth: 3, threads: 2, moves: allowedMoves})
+				s.Start()
+				Expect(s.deepestLine(nil)[0]).To(Equal(allowedMoves[0]))
+			})
+		})
+	})
+	Describe("searchmoves", func() {
+		It("only returns moves from the restricted set", func() {
+			allowedMoves := []Move{NewNormalMove(SQ_A2, SQ_A3), NewNormalMove(SQ_H2, SQ_H3)}
+			s := newTestSearch("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1", &SearchConstraints{moves: allowedMoves})
+			for s.depth < 4 {
+				s.ToNextDepth()
+				_, line, halted := s.searchToDepth(s.Root, s.depth)
+				Expect(halted).To(BeFalse())
+				Expect(line).ToNot(BeEmpty())
+				Expect(allowedMoves).To(ContainElement(line[0]))
+			}
+		})
+		It("ignores root entries from an unrestricted search", func() {
+			pos, posErr := FromFEN("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1")
+			Expect(posErr).ToNot(HaveOccurred())
+			tt := NewTranspTable(MIN_HASH_MB)
+			unrestricted := NewSearch(pos, &SearchConstraints{}, tt)
+			unrestricted.ToNextDepth()
+			unrestricted.ToNextDepth()
+			_, line, _ := unrestricted.searchToDepth(pos, 2)
+			Expect(line[0]).To(Equal(NewNormalMove(SQ_B3, SQ_D5)))
+
+			allowedMoves := []Move{NewNormalMove(SQ_A2, SQ_A3)}
+			restricted := NewSearch(pos, &SearchConstraints{moves: allowedMoves}, tt)
+			restricted.ToNextDepth()
+			_, line, _ = restricted.searchToDepth(pos, 1)
+			Expect(line[0]).To(Equal(allowedMoves[0]))
+		})
+	})
+	Describe("::searchPVsToDepth", func() {
+		It("returns a distinct root move per PV, best first", func() {
+			s := newTestSearch("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1", &SearchConstraints{multiPV: 3})
+			s.ToNextDepth()
+			s.ToNextDepth()
+			pvs, halted := s.searchPVsToDepth(s.Root, 2)
+			Expect(halted).To(BeFalse())
+			Expect(pvs).To(HaveLen(3))
+			Expect(pvs[0].Line[0]).To(Equal(NewNormalMove(SQ_B3, SQ_D5)))
+			rootMoves := make(map[Move]bool)
+			for pvIdx, pv := range pvs {
+				Expect(rootMoves).ToNot(HaveKey(pv.Line[0]))
+				rootMoves[pv.Line[0]] = true
+				if pvIdx > 0 {
+					Expect(pv.Score).To(BeNumerically("<=", pvs[pvIdx-1].Score))
+				}
+			}
+		})
+		When("searchmoves allows fewer moves than MultiPV", func() {
+			It("returns a PV per allowed move", func() {
+				allowedMoves := []Move{NewNormalMove(SQ_A2, SQ_A3), NewNormalMove(SQ_H2, SQ_H3)}
+				s := newTestSearch("4k3/8/8/3q4/8/1Q6/P6P/6K1 w - - 0 1", &SearchConstraints{moves: allowedMoves, multiPV: 3})
+				s.ToNextDepth()
+				pvs, halted := s.searchPVsToDepth(s.Root, 1)
+				Expect(halted).To(BeFalse())
+				Expect(pvs).To(HaveLen(2))
+				Expect(allowedMoves).To(ContainElement(pvs[0].Line[0]))
+				Expect(allowedMoves).To(ContainElement(pvs[1].Line[0]))
+			})
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package main
+
+// SEE (static exchange evaluation) returns the material the side to move is expected to
+// win by making the move, assuming both sides keep recapturing on the move's end square
+// with their least valuable attacker, and either side stops as soon as recapturing would
+// lose material. Sliding pieces lined up behind a capturer join the exchange once the
+// capturer has moved (x-rays). Pins are not considered.
+func (p *Position) SEE(move Move) int16 {
+	start := move.StartSq()
+	end := move.EndSq()
+	occupied := p.OccupiedBB() &^ BBWithSquares(start)
+
+	var gains [32]int16
+	onSquare := p.pieces[start].Type()
+	if move.Type() == CAPTURES_EN_PASSANT {
+		gains[0] = PieceTypeToVal(PAWN)
+		occupied &^= BBWithSquares(SqFromCoords(int(start.Rank()), int(end.File())))
+	} else {
+		gains[0] = PieceTypeToVal(p.pieces[end].Type())
+	}
+	if move.Type() == PAWN_PROMOTION {
+		onSquare = move.PromotedTo()
+		gains[0] += PieceTypeToVal(onSquare) - PieceTypeToVal(PAWN)
+	}
+
+	color := NewColor(p.isWhiteTurn).Opp()
+	attackers := p.attackersBB(end, occupied) & occupied
+	depth := 0
+	for depth < len(gains)-1 {
+		attackerSq, attacker := p.leastValuableAttacker(attackers&p.colorBitboards[color], color)
+		if attacker == EMPTY_PIECE_TYPE {
+			break
+		}
+		// the king can only recapture if nothing would be left to recapture it
+		if attacker == KING && attackers&p.colorBitboards[color.Opp()]&occupied != 0 {
+			break
+		}
+		depth++
+		gains[depth] = PieceTypeToVal(onSquare) - gains[depth-1]
+		// neither side can do better by continuing the exchange
+		if MaxInt(int(-gains[depth-1]), int(gains[depth])) < 0 {
+			break
+		}
+		onSquare = attacker
+		occupied &^= BBWithSquares(attackerSq)
+		attackers = p.attackersBB(end, occupied) & occupied
+		color = color.Opp()
+	}
+
+	// either side may stop the exchange instead of recapturing
+	for ; depth > 0; depth-- {
+		gains[depth-1] = -int16(MaxInt(int(-gains[depth-1]), int(gains[depth])))
+	}
+	return gains[0]
+}
+
+// IsLosingCapture returns true if the capture's SEE is negative. Capturing a piece worth
+// at least as much as the capturer can't lose material, so the SEE is skipped for those.
+func (p *Position) IsLosingCapture(move Move) bool {
+	victim := p.pieces[move.EndSq()].Type()
+	if move.Type() == CAPTURES_EN_PASSANT {
+		victim = PAWN
+	}
+	attacker := p.pieces[move.StartSq()].Type()
+	if move.Type() != PAWN_PROMOTION && PieceTypeToVal(victim) >= PieceTypeToVal(attacker) {
+		return false
+	}
+	return p.SEE(move) < 0
+}
+
+// attackersBB returns the pieces of both colors attacking the square, with sliding piece
+// attacks blocked by the given occupancy
+func (p *Position) attackersBB(sq Square, occupied Bitboard) Bitboard {
+	diagSliders := p.pieceBitboards[W_BISHOP] | p.pieceBitboards[B_BISHOP] |
+		p.pieceBitboards[W_QUEEN] | p.pieceBitboards[B_QUEEN]
+	straightSliders := p.pieceBitboards[W_ROOK] | p.pieceBitboards[B_ROOK] |
+		p.pieceBitboards[W_QUEEN] | p.pieceBitboards[B_QUEEN]
+	return PawnAttacksBB(sq, BLACK)&p.pieceBitboards[W_PAWN] |
+		PawnAttacksBB(sq, WHITE)&p.pieceBitboards[B_PAWN] |
+		KnightAttacksBB(sq)&(p.pieceBitboards[W_KNIGHT]|p.pieceBitboards[B_KNIGHT]) |
+		KingAttacksBB(sq)&(p.pieceBitboards[W_KING]|p.pieceBitboards[B_KING]) |
+		SlidingAttacksBB(occupied, sq, BISHOP)&diagSliders |
+		SlidingAttacksBB(occupied, sq, ROOK)&straightSliders
+}
+
+func (p *Position) leastValuableAttacker(attackers Bitboard, color Color) (Square, PieceType) {
+	for pt := PAWN; pt <= KING; pt++ {
+		pieceAttackers := attackers & p.pieceBitboards[NewPiece(pt, color)]
+		if pieceAttackers != 0 {
+			return pieceAttackers.FirstSq(), pt
+		}
+	}
+	return NULL_SQ, EMPTY_PIECE_TYPE
+}
+
+// MVVLVA scores a capture by the most valuable victim, breaking ties with the least
+// valuable attacker, so that cheap pieces capturing expensive ones are tried first
+func MVVLVA(pos *Position, move Move) int16 {
+	victim := pos.pieces[move.EndSq()].Type()
+	if move.Type() == CAPTURES_EN_PASSANT {
+		victim = PAWN
+	}
+	attacker := pos.pieces[move.StartSq()].Type()
+	return PieceTypeToVal(victim)*int16(N_PIECE_TYPES) - int16(attacker) + PieceTypeToVal(move.PromotedTo())
+}
@@ -0,0 +1,83 @@
+package main_test
+
+import (
+	"github.com/CameronHonis/Mila"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SEE", func() {
+	see := func(fen string, move main.Move) int16 {
+		pos, posErr := main.FromFEN(fen)
+		Expect(posErr).ToNot(HaveOccurred())
+		return pos.SEE(move)
+	}
+	When("the captured piece is undefended", func() {
+		It("wins the captured piece", func() {
+			Expect(see("4k3/8/8/3p4/8/8/8/3RK3 w - - 0 1", main.NewNormalMove(main.SQ_D1, main.SQ_D5))).To(Equal(main.PAWN_VAL))
+		})
+	})
+	When("the captured piece is defended", func() {
+		It("loses the capturer", func() {
+			Expect(see("4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1", main.NewNormalMove(main.SQ_D1, main.SQ_D5))).
+				To(Equal(main.PAWN_VAL - main.QUEEN_VAL))
+		})
+		It("trades evenly with an equal capturer", func() {
+			Expect(see("4k3/8/4p3/3n4/8/4N3/8/4K3 w - - 0 1", main.NewNormalMove(main.SQ_E3, main.SQ_D5))).To(Equal(int16(0)))
+		})
+	})
+	When("a sliding piece is lined up behind the capturer", func() {
+		It("counts the x-ray recapture", func() {
+			// RxR RxR RxR KxR is even, and without the black rook the king can't recapture
+			fen := "3rk3/3r4/8/8/8/8/3R4/3RK3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_D2, main.SQ_D7))).To(Equal(int16(0)))
+			fen = "4k3/3r4/8/8/8/8/3R4/3RK3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_D2, main.SQ_D7))).To(Equal(main.ROOK_VAL))
+		})
+		It("counts a queen behind a bishop", func() {
+			// BxN PxB QxP: the queen only sees the pawn once the bishop has moved off the diagonal
+			fen := "4k3/8/5p2/4n3/3B4/2Q5/8/4K3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_D4, main.SQ_E5))).To(Equal(main.KNIGHT_VAL - main.BISHOP_VAL + main.PAWN_VAL))
+		})
+	})
+	When("recapturing would lose material", func() {
+		It("stops the exchange", func() {
+			// QxN would be answered by RxQ, so black keeps the queen and gives up the pawn
+			fen := "3qk3/8/8/3p4/8/4N3/8/3RK3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_E3, main.SQ_D5))).To(Equal(main.PAWN_VAL))
+		})
+	})
+	When("the king is the last recapturer", func() {
+		It("does not recapture into a defended square", func() {
+			fen := "4k3/3p4/8/8/8/8/3R4/3RK3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_D2, main.SQ_D7))).To(Equal(main.PAWN_VAL))
+		})
+		It("recaptures an undefended piece", func() {
+			fen := "8/3pk3/8/8/8/8/3R4/4K3 w - - 0 1"
+			Expect(see(fen, main.NewNormalMove(main.SQ_D2, main.SQ_D7))).To(Equal(main.PAWN_VAL - main.ROOK_VAL))
+		})
+	})
+	It("counts the pawn captured en passant", func() {
+		fen := "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2"
+		Expect(see(fen, main.NewEnPassantMove(main.SQ_E5, main.SQ_D6))).To(Equal(main.PAWN_VAL))
+	})
+	It("weighs the pieces by the eval params in use", func() {
+		params := main.DefaultEvalParams()
+		params.KnightVal = 400
+		DeferCleanup(main.SetEvalParams, main.DefaultEvalParams())
+		main.SetEvalParams(params)
+		fen := "4k3/8/4p3/3n4/8/8/8/3RK3 w - - 0 1"
+		Expect(see(fen, main.NewNormalMove(main.SQ_D1, main.SQ_D5))).To(Equal(400 - main.ROOK_VAL))
+	})
+})
+
+var _ = Describe("Position::IsLosingCapture", func() {
+	It("is false for a capture of a more valuable piece", func() {
+		pos, _ := main.FromFEN("4k3/8/4p3/3r4/8/8/8/3NK3 w - - 0 1")
+		Expect(pos.IsLosingCapture(main.NewNormalMove(main.SQ_D1, main.SQ_D5))).To(BeFalse())
+	})
+	It("is true for a capture of a defended, less valuable piece", func() {
+		pos, _ := main.FromFEN("4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1")
+		Expect(pos.IsLosingCapture(main.NewNormalMove(main.SQ_D1, main.SQ_D5))).To(BeTrue())
+	})
+})
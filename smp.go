@@ -0,0 +1,82 @@
+package main
+
+// searchResult is the line found by a thread on its deepest completed iteration
+type searchResult struct {
+	depth uint8
+	line  []Move
+}
+
+// startHelpers launches Threads()-1 helper searches alongside the main search (Lazy SMP).
+// Helpers share the transposition table but search their own copy of the root, so the
+// main search benefits from the entries they post. Every other helper starts one depth
+// ahead, so the threads don't all search the same tree in lockstep. Helpers keep to the
+// depth limit, so that the line reported never goes deeper than the GUI asked for.
+func (s *Search) startHelpers() {
+	nHelpers := s.Constraints.Threads() - 1
+	if nHelpers <= 0 {
+		return
+	}
+	helpers := make([]*Search, 0, nHelpers)
+	for helperIdx := 0; helperIdx < nHelpers; helperIdx++ {
+		constraints := &SearchConstraints{
+			moves:       s.Constraints.moves,
+			maxDepth:    s.Constraints.maxDepth,
+			lmrStrength: s.Constraints.lmrStrength,
+		}
+		helper := NewSearch(s.Root.Copy(), constraints, s.TT)
+		helper.depth = uint8(helperIdx % 2)
+		helpers = append(helpers, helper)
+	}
+	s.helpers = helpers
+
+	for _, helper := range s.helpers {
+		s.helperWg.Add(1)
+		go func(helper *Search) {
+			defer s.helperWg.Done()
+			helper.searchIteratively()
+		}(helper)
+	}
+}
+
+// stopHelpers halts all helper searches and blocks until they have unwound
+func (s *Search) stopHelpers() {
+	for _, helper := range s.helpers {
+		helper.halt()
+	}
+	s.helperWg.Wait()
+}
+
+// searchIteratively is the helper's equivalent of Start, deepening until halted without
+// reporting anything but its last completed line.
+func (s *Search) searchIteratively() {
+	for s.depth < s.Constraints.DepthLmt() {
+		s.ToNextDepth()
+		score, line, halted := s.searchToDepth(s.Root, s.depth)
+		if halted || len(line) == 0 {
+			return
+		}
+		s.lastResult.Store(&searchResult{depth: s.depth, line: line})
+		if IsMateScore(score) && MatePlies(score) <= int(s.depth) {
+			return
+		}
+	}
+}
+
+// deepestLine returns the line from the thread that completed the deepest iteration,
+// preferring the main search on ties.
+func (s *Search) deepestLine(line []Move) []Move {
+	var bestResult = s.lastResult.Load()
+	for _, helper := range s.helpers {
+		result := helper.lastResult.Load()
+		if result == nil {
+			continue
+		}
+		if bestResult == nil || result.depth > bestResult.depth {
+			bestResult = result
+		}
+	}
+	if bestResult == nil {
+		return line
+	}
+	return bestResult.line
+}
@@ -144,3 +144,19 @@ func (s Square) IsDark() bool {
 		return s.File()%2 == 1
 	}
 }
+
+// DistanceTo returns the number of king moves between the squares on an empty board
+func (s Square) DistanceTo(other Square) int {
+	rankDist := int(s.Rank()) - int(other.Rank())
+	fileDist := int(s.File()) - int(other.File())
+	return MaxInt(MaxInt(rankDist, -rankDist), MaxInt(fileDist, -fileDist))
+}
+
+// RelativeRank returns the rank as seen from the color's side of the board, from 1 on
+// the color's back rank to 8 on the opponent's
+func (s Square) RelativeRank(color Color) uint8 {
+	if color == WHITE {
+		return s.Rank()
+	}
+	return N_RANKS + 1 - s.Rank()
+}
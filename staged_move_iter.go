@@ -0,0 +1,236 @@
+package main
+
+// MoveStage is a group of moves yielded together by a StagedMoveIter
+type MoveStage uint8
+
+const (
+	TT_MOVE_STAGE MoveStage = iota
+	GEN_CAPTURES_STAGE
+	GOOD_CAPTURES_STAGE
+	REFUTATIONS_STAGE
+	GEN_QUIETS_STAGE
+	QUIETS_STAGE
+	BAD_CAPTURES_STAGE
+	DONE_STAGE
+)
+
+// StagedMoveIter yields the legal moves of a position from most to least promising: the
+// TT move, captures and promotions that don't lose material by MVVLVA, the killers and
+// the countermove, the remaining quiet moves by history, and finally the losing captures.
+// Each stage's moves are only generated once the previous stages run out, so a beta
+// cutoff on an early move skips generating the rest, and a cutoff on the TT move skips
+// move generation entirely. Moves within a stage are picked best first as they're needed,
+// rather than sorted up front.
+type StagedMoveIter struct {
+	pos            *Position
+	legality       *Legality
+	tables         *MoveOrderTables
+	ply            Ply
+	ttMove         Move
+	refutations    [N_KILLERS + 1]Move
+	isAllowed      func(Move) bool
+	isCapturesOnly bool
+	isGoodOnly     bool
+
+	stage       MoveStage
+	moves       []Move
+	scores      []int32
+	idx         int
+	badCaptures []Move
+}
+
+// NewStagedMoveIter iterates over every legal move, consulting the tables for the killers
+// at ply and the countermove to prevMove, the move that led to the position
+func NewStagedMoveIter(pos *Position, tables *MoveOrderTables, ttMove Move, ply Ply, prevMove Move) *StagedMoveIter {
+	iter := &StagedMoveIter{
+		pos:      pos,
+		legality: NewLegality(pos),
+		tables:   tables,
+		ply:      ply,
+		ttMove:   ttMove,
+		stage:    TT_MOVE_STAGE,
+	}
+	if KILLERS_ENABLED && ply < MAX_SEARCH_PLY {
+		copy(iter.refutations[:N_KILLERS], tables.killers[ply][:])
+	}
+	if COUNTER_MOVES_ENABLED {
+		iter.refutations[N_KILLERS] = tables.CounterMove(pos, prevMove)
+	}
+	return iter
+}
+
+// NewStagedCaptureIter iterates over the legal captures and promotions only. If isGoodOnly,
+// the captures that lose material by SEE are skipped as well.
+func NewStagedCaptureIter(pos *Position, tables *MoveOrderTables, isGoodOnly bool) *StagedMoveIter {
+	return &StagedMoveIter{
+		pos:            pos,
+		legality:       NewLegality(pos),
+		tables:         tables,
+		ttMove:         NULL_MOVE,
+		isCapturesOnly: true,
+		isGoodOnly:     isGoodOnly,
+		stage:          GEN_CAPTURES_STAGE,
+	}
+}
+
+// Restrict skips the moves that isAllowed returns false for
+func (iter *StagedMoveIter) Restrict(isAllowed func(Move) bool) {
+	iter.isAllowed = isAllowed
+}
+
+func (iter *StagedMoveIter) Next() (move Move, done bool) {
+	for {
+		pMove, ok := iter.nextPseudoLegal()
+		if !ok {
+			return NULL_MOVE, true
+		}
+		if iter.isAllowed != nil && !iter.isAllowed(pMove) {
+			continue
+		}
+		if iter.legality.IsLegal(pMove) {
+			return pMove, false
+		}
+	}
+}
+
+// Remaining returns the number of moves generated but not yet yielded. Moves in stages
+// that haven't been generated yet aren't counted.
+func (iter *StagedMoveIter) Remaining() int {
+	remaining := len(iter.badCaptures)
+	if iter.stage == GOOD_CAPTURES_STAGE || iter.stage == QUIETS_STAGE {
+		remaining += len(iter.moves) - iter.idx
+	} else if iter.stage == BAD_CAPTURES_STAGE {
+		remaining -= iter.idx
+	}
+	return remaining
+}
+
+func (iter *StagedMoveIter) nextPseudoLegal() (Move, bool) {
+	for {
+		switch iter.stage {
+		case TT_MOVE_STAGE:
+			iter.stage = GEN_CAPTURES_STAGE
+			if iter.ttMove != NULL_MOVE && iter.pos.IsPseudoLegalMove(iter.ttMove) {
+				return iter.ttMove, true
+			}
+		case GEN_CAPTURES_STAGE:
+			iter.load(GenPseudoLegalCaptures(iter.pos), true)
+			iter.stage = GOOD_CAPTURES_STAGE
+		case GOOD_CAPTURES_STAGE:
+			for iter.idx < len(iter.moves) {
+				move := iter.pickBest()
+				if move == iter.ttMove {
+					continue
+				}
+				if SEE_ORDERING_ENABLED && iter.pos.IsLosingCapture(move) {
+					iter.badCaptures = append(iter.badCaptures, move)
+					continue
+				}
+				return move, true
+			}
+			iter.idx = 0
+			if iter.isCapturesOnly {
+				iter.stage = BAD_CAPTURES_STAGE
+			} else {
+				iter.stage = REFUTATIONS_STAGE
+			}
+		case REFUTATIONS_STAGE:
+			for iter.idx < len(iter.refutations) {
+				move := iter.refutations[iter.idx]
+				iter.idx++
+				if iter.isRefutation(move, iter.idx-1) {
+					return move, true
+				}
+			}
+			iter.stage = GEN_QUIETS_STAGE
+		case GEN_QUIETS_STAGE:
+			iter.load(GenPseudoLegalQuiets(iter.pos), false)
+			iter.stage = QUIETS_STAGE
+		case QUIETS_STAGE:
+			for iter.idx < len(iter.moves) {
+				move := iter.pickBest()
+				if move == iter.ttMove || iter.isYieldedRefutation(move) {
+					continue
+				}
+				return move, true
+			}
+			iter.idx = 0
+			iter.stage = BAD_CAPTURES_STAGE
+		case BAD_CAPTURES_STAGE:
+			if iter.isGoodOnly || iter.idx >= len(iter.badCaptures) {
+				iter.stage = DONE_STAGE
+				continue
+			}
+			move := iter.badCaptures[iter.idx]
+			iter.idx++
+			return move, true
+		default:
+			return NULL_MOVE, false
+		}
+	}
+}
+
+// load replaces the stage's moves with the given moves, scored by MVVLVA for captures or
+// by history for quiet moves
+func (iter *StagedMoveIter) load(moves []Move, isCaptures bool) {
+	iter.moves = moves
+	iter.idx = 0
+	if cap(iter.scores) < len(moves) {
+		iter.scores = make([]int32, len(moves))
+	}
+	iter.scores = iter.scores[:len(moves)]
+	color := NewColor(iter.pos.isWhiteTurn)
+	for moveIdx, move := range moves {
+		if isCaptures {
+			iter.scores[moveIdx] = int32(MVVLVA(iter.pos, move))
+		} else if HISTORY_ENABLED {
+			iter.scores[moveIdx] = iter.tables.History(color, move)
+		}
+	}
+}
+
+// pickBest swaps the best scored move left in the stage to the front of the remaining
+// moves and yields it. A full sort is wasted work whenever an early move cuts off.
+func (iter *StagedMoveIter) pickBest() Move {
+	if MOVE_SORT_ENABLED {
+		bestIdx := iter.idx
+		for moveIdx := iter.idx + 1; moveIdx < len(iter.moves); moveIdx++ {
+			if iter.scores[moveIdx] > iter.scores[bestIdx] {
+				bestIdx = moveIdx
+			}
+		}
+		iter.moves[iter.idx], iter.moves[bestIdx] = iter.moves[bestIdx], iter.moves[iter.idx]
+		iter.scores[iter.idx], iter.scores[bestIdx] = iter.scores[bestIdx], iter.scores[iter.idx]
+	}
+	move := iter.moves[iter.idx]
+	iter.idx++
+	return move
+}
+
+// isRefutation returns true if the killer or countermove at refutationIdx should be yielded.
+// The tables are shared across positions, so the move is validated for this position.
+func (iter *StagedMoveIter) isRefutation(move Move, refutationIdx int) bool {
+	if move == NULL_MOVE || move == iter.ttMove {
+		return false
+	}
+	for _, prevRefutation := range iter.refutations[:refutationIdx] {
+		if prevRefutation == move {
+			return false
+		}
+	}
+	if iter.pos.IsCapture(move) || move.Type() == PAWN_PROMOTION {
+		return false
+	}
+	return iter.pos.IsPseudoLegalMove(move)
+}
+
+// isYieldedRefutation returns true if a quiet move was already yielded as a refutation.
+// Every pseudo-legal quiet refutation is yielded, so only membership needs checking.
+func (iter *StagedMoveIter) isYieldedRefutation(move Move) bool {
+	for _, refutation := range iter.refutations {
+		if refutation == move {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StagedMoveIter", func() {
+	var mot *MoveOrderTables
+	var pos *Position
+	BeforeEach(func() {
+		mot = &MoveOrderTables{}
+		var posErr error
+		pos, posErr = FromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+		Expect(posErr).ToNot(HaveOccurred())
+	})
+	drain := func(iter interface{ Next() (Move, bool) }) []Move {
+		moves := make([]Move, 0)
+		for {
+			move, done := iter.Next()
+			if done {
+				return moves
+			}
+			moves = append(moves, move)
+		}
+	}
+	It("orders the TT move, killers, the countermove, quiets by history, then losing captures", func() {
+		ttMove := NewNormalMove(SQ_D2, SQ_D4)
+		losingCapture := NewNormalMove(SQ_F3, SQ_E5)
+		killer := NewNormalMove(SQ_B1, SQ_C3)
+		prevMove := NewNormalMove(SQ_B8, SQ_C6)
+		counterMove := NewNormalMove(SQ_F1, SQ_B5)
+		historyMove := NewNormalMove(SQ_H2, SQ_H3)
+		mot.RecordCutoff(pos, 5, 1, NULL_MOVE, killer, nil)
+		mot.RecordCutoff(pos, 6, 1, prevMove, counterMove, nil)
+		mot.RecordCutoff(pos, 7, 3, NULL_MOVE, historyMove, nil)
+
+		moves := drain(NewStagedMoveIter(pos, mot, ttMove, 5, prevMove))
+		Expect(moves[:4]).To(Equal([]Move{ttMove, killer, counterMove, historyMove}))
+		Expect(moves[len(moves)-1]).To(Equal(losingCapture))
+		Expect(moves).To(ConsistOf(drain(NewLegalMoveIter(pos))))
+	})
+	It("orders captures that don't lose material before quiet moves, by MVV-LVA", func() {
+		pos, _ = FromFEN("4k3/8/3p4/2n1r3/1P1B4/8/8/6K1 w - - 0 1")
+		killer := NewNormalMove(SQ_G1, SQ_G2)
+		mot.RecordCutoff(pos, 5, 1, NULL_MOVE, killer, nil)
+
+		moves := drain(NewStagedMoveIter(pos, mot, NULL_MOVE, 5, NULL_MOVE))
+		bxe5 := NewNormalMove(SQ_D4, SQ_E5)
+		bxc5 := NewNormalMove(SQ_B4, SQ_C5)
+		Expect(moves[:3]).To(Equal([]Move{bxe5, bxc5, killer}))
+		// the bishop is worth more than the knight it takes, and the pawn recaptures
+		Expect(moves[len(moves)-1]).To(Equal(NewNormalMove(SQ_D4, SQ_C5)))
+	})
+	It("does not generate any moves before yielding the TT move", func() {
+		ttMove := NewNormalMove(SQ_D2, SQ_D4)
+		iter := NewStagedMoveIter(pos, mot, ttMove, 0, NULL_MOVE)
+		move, done := iter.Next()
+		Expect(done).To(BeFalse())
+		Expect(move).To(Equal(ttMove))
+		Expect(iter.moves).To(BeNil())
+	})
+	It("skips a TT move or killer that isn't legal in the position", func() {
+		// both moves are from another position, where the knight on b1 had moved to c3
+		ttMove := NewNormalMove(SQ_C3, SQ_D5)
+		mot.RecordCutoff(pos, 0, 1, NULL_MOVE, NewNormalMove(SQ_C3, SQ_B5), nil)
+		moves := drain(NewStagedMoveIter(pos, mot, ttMove, 0, NULL_MOVE))
+		Expect(moves).To(ConsistOf(drain(NewLegalMoveIter(pos))))
+	})
+	It("skips the moves it's restricted from", func() {
+		excluded := NewNormalMove(SQ_D2, SQ_D4)
+		iter := NewStagedMoveIter(pos, mot, excluded, 0, NULL_MOVE)
+		iter.Restrict(func(move Move) bool {
+			return move != excluded
+		})
+		moves := drain(iter)
+		Expect(moves).ToNot(ContainElement(excluded))
+		Expect(moves).To(HaveLen(len(drain(NewLegalMoveIter(pos))) - 1))
+	})
+	Describe("NewStagedCaptureIter", func() {
+		It("yields only captures and promotions", func() {
+			pos, _ = FromFEN("4k3/1P6/3p4/2n1r3/1P1B4/8/8/6K1 w - - 0 1")
+			moves := drain(NewStagedCaptureIter(pos, mot, false))
+			Expect(moves).To(ContainElement(NewNormalMove(SQ_D4, SQ_C5)))
+			Expect(moves).To(ContainElement(NewMove(SQ_B7, SQ_B8, NULL_SQ, QUEEN, false)))
+			for _, move := range moves {
+				Expect(pos.IsCapture(move) || move.Type() == PAWN_PROMOTION).To(BeTrue())
+			}
+		})
+		It("skips losing captures if only the good ones are wanted", func() {
+			pos, _ = FromFEN("4k3/8/3p4/2n1r3/1P1B4/8/8/6K1 w - - 0 1")
+			moves := drain(NewStagedCaptureIter(pos, mot, true))
+			Expect(moves).To(ConsistOf(NewNormalMove(SQ_D4, SQ_E5), NewNormalMove(SQ_B4, SQ_C5)))
+		})
+	})
+})
+
+var _ = Describe("GenPseudoLegalCaptures and GenPseudoLegalQuiets", func() {
+	It("together generate every pseudo-legal move of the perft positions", func() {
+		file, fileErr := os.Open("./perft")
+		Expect(fileErr).ToNot(HaveOccurred())
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fen := strings.TrimSpace(strings.Split(scanner.Text(), ";")[0])
+			pos, posErr := FromFEN(fen)
+			Expect(posErr).ToNot(HaveOccurred())
+			// the children cover positions with the other side to move as well
+			positions := []*Position{pos}
+			iter := NewLegalMoveIter(pos)
+			for {
+				move, done := iter.Next()
+				if done {
+					break
+				}
+				child := pos.Copy()
+				child.MakeMove(move)
+				positions = append(positions, child)
+			}
+			for _, pos := range positions {
+				moves := append(GenPseudoLegalCaptures(pos), GenPseudoLegalQuiets(pos)...)
+				Expect(moves).To(ConsistOf(GenPseudoLegalMoves(pos)), pos.FEN())
+			}
+		}
+	})
+})
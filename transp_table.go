@@ -2,93 +2,265 @@ package main
 
 import (
 	"log"
-	"sync"
+	"sync/atomic"
+	"unsafe"
 )
 
+const DEFAULT_HASH_MB = 64
+const MIN_HASH_MB = 1
+const MAX_HASH_MB = 4096
+
+// TT_BUCKET_SIZE is the number of entries that share a single table index.
+// The first slot in a bucket is depth-preferred, the second is always-replace.
+const TT_BUCKET_SIZE = 2
+
+// ttSlot stores a single packed TTEntry alongside a verification key. The key is
+// stored xor'd with the data, so that a slot written concurrently by another
+// search is detected as a miss instead of being read as a mismatched entry.
+// The data bit layout is as follows:
+// - bits 1-16 represent the move
+// - bits 17-32 represent the score
+// - bits 33-40 represent the depth
+// - bits 41-42 represent the entry type (exact, lower bound or upper bound)
+// - bits 49-56 represent the generation the entry was written in
+// - bit 64 is set if the slot is occupied
+type ttSlot struct {
+	check atomic.Uint64
+	data  atomic.Uint64
+}
+
+type ttBucket [TT_BUCKET_SIZE]ttSlot
+
+const ttOccupiedBit = uint64(1) << 63
+
 type TranspTable struct {
-	entryByHash map[ZHash]TTEntry
-	mu          sync.Mutex
+	buckets []ttBucket
+	mask    uint64
+	gen     atomic.Uint32
 }
 
-func NewTranspTable() *TranspTable {
-	return &TranspTable{
-		entryByHash: make(map[ZHash]TTEntry),
+func NewTranspTable(sizeMb int) *TranspTable {
+	tt := &TranspTable{}
+	tt.Resize(sizeMb)
+	return tt
+}
+
+// Resize reallocates the table to the largest power of two number of buckets
+// that fits in sizeMb megabytes. All existing entries are discarded.
+func (tt *TranspTable) Resize(sizeMb int) {
+	sizeMb = MaxInt(MIN_HASH_MB, MinInt(sizeMb, MAX_HASH_MB))
+	maxBuckets := uint64(sizeMb) * 1024 * 1024 / uint64(unsafe.Sizeof(ttBucket{}))
+	nBuckets := uint64(1)
+	for nBuckets*2 <= maxBuckets {
+		nBuckets *= 2
 	}
+	tt.buckets = make([]ttBucket, nBuckets)
+	tt.mask = nBuckets - 1
+	tt.gen.Store(0)
 }
 
-func (tt *TranspTable) PostResults(hash ZHash, score int16, isLowerBound bool, move Move, depth uint8) {
-	tt.mu.Lock()
-	defer tt.mu.Unlock()
-	prevEntry, ok := tt.entryByHash[hash]
-	if ok {
-		if prevEntry.Depth > depth {
-			return
+func (tt *TranspTable) Clear() {
+	tt.buckets = make([]ttBucket, len(tt.buckets))
+	tt.gen.Store(0)
+}
+
+// NextGen ages every entry in the table by one generation. It should be called
+// once per search, so that entries from previous searches are replaced first.
+func (tt *TranspTable) NextGen() {
+	tt.gen.Add(1)
+}
+
+func (tt *TranspTable) PostResults(hash ZHash, score int16, entryType TTEntryType, move Move, depth uint8) {
+	gen := uint8(tt.gen.Load())
+	newEntry := TTEntry{
+		Score: score,
+		Type:  entryType,
+		Depth: depth,
+		Move:  move,
+		gen:   gen,
+	}
+	bucket := tt.bucket(hash)
+
+	for slotIdx := range bucket {
+		slot := &bucket[slotIdx]
+		prevEntry, ok := slot.load(hash)
+		if !ok {
+			continue
 		}
-		isBetterEst := !prevEntry.IsExact() && !isLowerBound
-		if prevEntry.Depth == depth && !isBetterEst {
-			return
+		if prevEntry.gen == gen {
+			if prevEntry.Depth > depth {
+				return
+			}
+			isBetterEst := !prevEntry.IsExact() && entryType == EXACT_ENTRY
+			if prevEntry.Depth == depth && !isBetterEst {
+				return
+			}
 		}
+		slot.store(hash, newEntry)
+		return
 	}
 
-	tt.entryByHash[hash] = TTEntry{
-		Score:        score,
-		IsLowerBound: isLowerBound,
-		Depth:        depth,
-		Move:         move,
+	depthPreferred := &bucket[0]
+	prevData := depthPreferred.data.Load()
+	prevEntry := unpackTTEntry(prevData)
+	if prevData&ttOccupiedBit == 0 || prevEntry.gen != gen || depth >= prevEntry.Depth {
+		depthPreferred.store(hash, newEntry)
+		return
 	}
+	bucket[1].store(hash, newEntry)
 }
 
 func (tt *TranspTable) GetEntry(hash ZHash) (entry TTEntry, exists bool) {
-	tt.mu.Lock()
-	defer tt.mu.Unlock()
-	var ok bool
-	if entry, ok = tt.entryByHash[hash]; ok {
-		return entry, true
+	bucket := tt.bucket(hash)
+	for slotIdx := range bucket {
+		if entry, exists = bucket[slotIdx].load(hash); exists {
+			return
+		}
 	}
 	return
 }
 
+// Line follows the best moves stored in the table from pos, stopping early if an
+// entry was overwritten or its move is no longer legal.
 func (tt *TranspTable) Line(pos *Position, depth uint8) []Move {
 	nMoves := depth
-	frozenPoss := make([]*FrozenPos, nMoves)
-	capturedPieces := make([]Piece, nMoves)
-	line := make([]Move, nMoves)
+	frozenPoss := make([]*FrozenPos, 0, nMoves)
+	capturedPieces := make([]Piece, 0, nMoves)
+	line := make([]Move, 0, nMoves)
 	for moveIdx := uint8(0); moveIdx < nMoves; moveIdx++ {
 		entry, entryExists := tt.GetEntry(pos.hash)
 		if DEBUG {
 			if !entryExists {
 				log.Fatalf("could not get entry for line after %s at depth %d", pos.FEN(), depth)
 			}
-			if entry.Depth < depth {
-				log.Fatalf("entry depth (%d) lower than requested depth (%d) while building line", entry.Depth, depth)
+			if entry.Depth < depth-moveIdx {
+				log.Fatalf("entry depth (%d) lower than requested depth (%d) while building line", entry.Depth, depth-moveIdx)
 			}
 		}
-		line[moveIdx] = entry.Move
-		isLastMove := moveIdx == nMoves-1
-		if !isLastMove {
-			capturedPieces[moveIdx], frozenPoss[moveIdx] = pos.MakeMove(entry.Move)
+		if !entryExists || entry.Move == NULL_MOVE || !pos.HasLegalMove(entry.Move) {
+			break
 		}
+		line = append(line, entry.Move)
+		captPiece, frozenPos := pos.MakeMove(entry.Move)
+		capturedPieces = append(capturedPieces, captPiece)
+		frozenPoss = append(frozenPoss, frozenPos)
 	}
 
 	//undo moves on pos
-	for moveIdx := int(nMoves) - 2; moveIdx >= 0; moveIdx-- {
-		move := line[moveIdx]
-		captPiece := capturedPieces[moveIdx]
-		frozenPos := frozenPoss[moveIdx]
-		pos.UnmakeMove(move, frozenPos, captPiece)
+	for moveIdx := len(line) - 1; moveIdx >= 0; moveIdx-- {
+		pos.UnmakeMove(line[moveIdx], frozenPoss[moveIdx], capturedPieces[moveIdx])
 	}
 	return line
 }
 
+// ScoreToTT converts a mate score measured from the search root into one measured
+// from the node being stored, which is ply plies from the root. This keeps the mate
+// distance correct when the entry is later probed at a different distance from the root.
+func ScoreToTT(score int16, ply Ply) int16 {
+	if score >= MATE_VAL-MAX_MATE_PLY {
+		return score + int16(ply)
+	} else if score <= -MATE_VAL+MAX_MATE_PLY {
+		return score - int16(ply)
+	}
+	return score
+}
+
+// ScoreFromTT is the inverse of ScoreToTT
+func ScoreFromTT(score int16, ply Ply) int16 {
+	if score >= MATE_VAL-MAX_MATE_PLY {
+		return score - int16(ply)
+	} else if score <= -MATE_VAL+MAX_MATE_PLY {
+		return score + int16(ply)
+	}
+	return score
+}
+
+func (tt *TranspTable) bucket(hash ZHash) *ttBucket {
+	return &tt.buckets[uint64(hash)&tt.mask]
+}
+
+func (slot *ttSlot) load(hash ZHash) (entry TTEntry, ok bool) {
+	data := slot.data.Load()
+	check := slot.check.Load()
+	if data&ttOccupiedBit == 0 || check^data != uint64(hash) {
+		return
+	}
+	return unpackTTEntry(data), true
+}
+
+func (slot *ttSlot) store(hash ZHash, entry TTEntry) {
+	data := entry.pack()
+	slot.check.Store(uint64(hash) ^ data)
+	slot.data.Store(data)
+}
+
+// TTEntryType describes how an entry's score relates to the true score of the
+// position, given the alpha-beta window the position was searched with.
 type TTEntryType uint8
 
+const (
+	// EXACT_ENTRY scores fell within the window
+	EXACT_ENTRY TTEntryType = iota
+	// LOWER_BOUND_ENTRY scores failed high (score >= beta), the true score is at least the score
+	LOWER_BOUND_ENTRY
+	// UPPER_BOUND_ENTRY scores failed low (score <= alpha), the true score is at most the score
+	UPPER_BOUND_ENTRY
+)
+
+// NewTTEntryType classifies a score against the alpha-beta window that the node was
+// entered with. The window must not be narrowed while searching the node's moves.
+func NewTTEntryType(score, alpha, beta int16) TTEntryType {
+	if score >= beta {
+		return LOWER_BOUND_ENTRY
+	} else if score <= alpha {
+		return UPPER_BOUND_ENTRY
+	}
+	return EXACT_ENTRY
+}
+
 type TTEntry struct {
-	Score        int16
-	IsLowerBound bool
-	Depth        uint8
-	Move         Move
+	Score int16
+	Type  TTEntryType
+	Depth uint8
+	Move  Move
+	gen   uint8
 }
 
 func (e *TTEntry) IsExact() bool {
-	return !e.IsLowerBound
+	return e.Type == EXACT_ENTRY
+}
+
+func (e *TTEntry) IsLowerBound() bool {
+	return e.Type == LOWER_BOUND_ENTRY
+}
+
+func (e *TTEntry) IsUpperBound() bool {
+	return e.Type == UPPER_BOUND_ENTRY
+}
+
+// IsCutoff returns true if the entry's score alone is enough to resolve a search
+// with the given window.
+func (e *TTEntry) IsCutoff(alpha, beta int16) bool {
+	if e.IsExact() {
+		return true
+	} else if e.IsLowerBound() {
+		return e.Score >= beta
+	} else {
+		return e.Score <= alpha
+	}
+}
+
+func (e *TTEntry) pack() uint64 {
+	return uint64(e.Move) | uint64(uint16(e.Score))<<16 | uint64(e.Depth)<<32 | uint64(e.Type&0b11)<<40 |
+		uint64(e.gen)<<48 | ttOccupiedBit
+}
+
+func unpackTTEntry(data uint64) TTEntry {
+	return TTEntry{
+		Move:  Move(data),
+		Score: int16(uint16(data >> 16)),
+		Depth: uint8(data >> 32),
+		Type:  TTEntryType((data >> 40) & 0b11),
+		gen:   uint8(data >> 48),
+	}
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TranspTable", func() {
+	var tt *TranspTable
+	var move Move
+	BeforeEach(func() {
+		tt = NewTranspTable(MIN_HASH_MB)
+		move = NewNormalMove(SQ_E2, SQ_E4)
+	})
+	Describe("#NewTranspTable", func() {
+		It("allocates a power of two number of buckets within the size limit", func() {
+			nBuckets := len(tt.buckets)
+			Expect(nBuckets & (nBuckets - 1)).To(Equal(0))
+			Expect(nBuckets * TT_BUCKET_SIZE * 16).To(BeNumerically("<=", MIN_HASH_MB*1024*1024))
+			Expect(nBuckets * TT_BUCKET_SIZE * 16 * 2).To(BeNumerically(">", MIN_HASH_MB*1024*1024))
+		})
+	})
+	Describe("::GetEntry", func() {
+		It("returns the posted entry", func() {
+			tt.PostResults(ZHash(12345), -321, LOWER_BOUND_ENTRY, move, 7)
+			entry, exists := tt.GetEntry(ZHash(12345))
+			Expect(exists).To(BeTrue())
+			Expect(entry.Score).To(Equal(int16(-321)))
+			Expect(entry.Type).To(Equal(LOWER_BOUND_ENTRY))
+			Expect(entry.Depth).To(Equal(uint8(7)))
+			Expect(entry.Move).To(Equal(move))
+		})
+		When("another hash maps to the same bucket", func() {
+			It("does not return the other hash's entry", func() {
+				tt.PostResults(ZHash(12345), 10, EXACT_ENTRY, move, 3)
+				_, exists := tt.GetEntry(ZHash(12345) + ZHash(tt.mask+1))
+				Expect(exists).To(BeFalse())
+			})
+		})
+	})
+	Describe("::PostResults", func() {
+		var hashA, hashB, hashC ZHash
+		BeforeEach(func() {
+			hashA = ZHash(7)
+			hashB = hashA + ZHash(tt.mask+1)
+			hashC = hashB + ZHash(tt.mask+1)
+		})
+		When("a shallower entry maps to a bucket with a deeper entry", func() {
+			It("keeps the deeper entry", func() {
+				tt.PostResults(hashA, 10, EXACT_ENTRY, move, 5)
+				tt.PostResults(hashB, 20, EXACT_ENTRY, move, 2)
+				tt.PostResults(hashC, 30, EXACT_ENTRY, move, 1)
+				_, existsA := tt.GetEntry(hashA)
+				_, existsB := tt.GetEntry(hashB)
+				_, existsC := tt.GetEntry(hashC)
+				Expect(existsA).To(BeTrue())
+				Expect(existsB).To(BeFalse())
+				Expect(existsC).To(BeTrue())
+			})
+		})
+		When("the deeper entry is from a previous generation", func() {
+			It("replaces the deeper entry", func() {
+				tt.PostResults(hashA, 10, EXACT_ENTRY, move, 5)
+				tt.NextGen()
+				tt.PostResults(hashB, 20, EXACT_ENTRY, move, 2)
+				_, existsA := tt.GetEntry(hashA)
+				_, existsB := tt.GetEntry(hashB)
+				Expect(existsA).To(BeFalse())
+				Expect(existsB).To(BeTrue())
+			})
+		})
+		When("the same position is posted at a shallower depth", func() {
+			It("keeps the deeper result", func() {
+				tt.PostResults(hashA, 10, EXACT_ENTRY, move, 5)
+				tt.PostResults(hashA, 20, EXACT_ENTRY, move, 2)
+				entry, _ := tt.GetEntry(hashA)
+				Expect(entry.Score).To(Equal(int16(10)))
+			})
+		})
+		When("the same position is posted at the same depth", func() {
+			It("prefers an exact score over a bound", func() {
+				tt.PostResults(hashA, 10, EXACT_ENTRY, move, 5)
+				tt.PostResults(hashA, 20, UPPER_BOUND_ENTRY, move, 5)
+				entry, _ := tt.GetEntry(hashA)
+				Expect(entry.Type).To(Equal(EXACT_ENTRY))
+				Expect(entry.Score).To(Equal(int16(10)))
+
+				tt.PostResults(hashB, 20, UPPER_BOUND_ENTRY, move, 5)
+				tt.PostResults(hashB, 10, EXACT_ENTRY, move, 5)
+				entry, _ = tt.GetEntry(hashB)
+				Expect(entry.Type).To(Equal(EXACT_ENTRY))
+				Expect(entry.Score).To(Equal(int16(10)))
+			})
+		})
+	})
+	Describe("#NewTTEntryType", func() {
+		It("classifies scores against the window", func() {
+			Expect(NewTTEntryType(50, 0, 100)).To(Equal(EXACT_ENTRY))
+			Expect(NewTTEntryType(100, 0, 100)).To(Equal(LOWER_BOUND_ENTRY))
+			Expect(NewTTEntryType(0, 0, 100)).To(Equal(UPPER_BOUND_ENTRY))
+		})
+	})
+	Describe("TTEntry::IsCutoff", func() {
+		It("cuts on an exact score regardless of window", func() {
+			entry := TTEntry{Score: 50, Type: EXACT_ENTRY}
+			Expect(entry.IsCutoff(100, 200)).To(BeTrue())
+		})
+		It("cuts on a lower bound at or above beta", func() {
+			entry := TTEntry{Score: 200, Type: LOWER_BOUND_ENTRY}
+			Expect(entry.IsCutoff(100, 200)).To(BeTrue())
+			Expect(entry.IsCutoff(100, 300)).To(BeFalse())
+		})
+		It("cuts on an upper bound at or below alpha", func() {
+			entry := TTEntry{Score: 100, Type: UPPER_BOUND_ENTRY}
+			Expect(entry.IsCutoff(100, 200)).To(BeTrue())
+			Expect(entry.IsCutoff(50, 200)).To(BeFalse())
+		})
+	})
+	Describe("::Line", func() {
+		It("follows the stored moves and restores the position", func() {
+			pos := InitPos()
+			fen := pos.FEN()
+			firstMove := NewNormalMove(SQ_E2, SQ_E4)
+			secondMove := NewNormalMove(SQ_E7, SQ_E5)
+			tt.PostResults(pos.hash, 0, EXACT_ENTRY, firstMove, 2)
+			captPiece, frozenPos := pos.MakeMove(firstMove)
+			tt.PostResults(pos.hash, 0, EXACT_ENTRY, secondMove, 1)
+			pos.UnmakeMove(firstMove, frozenPos, captPiece)
+
+			Expect(tt.Line(pos, 3)).To(Equal([]Move{firstMove, secondMove}))
+			Expect(pos.FEN()).To(Equal(fen))
+		})
+	})
+	Describe("#ScoreToTT", func() {
+		It("stores mate scores relative to the node", func() {
+			Expect(ScoreToTT(MATE_VAL-5, 3)).To(Equal(MATE_VAL - 2))
+			Expect(ScoreToTT(-MATE_VAL+5, 3)).To(Equal(-MATE_VAL + 2))
+		})
+		It("leaves other scores alone", func() {
+			Expect(ScoreToTT(250, 3)).To(Equal(int16(250)))
+		})
+		It("is reversed by ScoreFromTT", func() {
+			Expect(ScoreFromTT(ScoreToTT(MATE_VAL-5, 3), 3)).To(Equal(MATE_VAL - 5))
+			Expect(ScoreFromTT(ScoreToTT(-MATE_VAL+5, 3), 3)).To(Equal(-MATE_VAL + 5))
+		})
+	})
+})
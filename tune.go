@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TuningEntry is a quiet position from a game, along with the result of that game from
+// white's point of view: 1 for a win, 0.5 for a draw and 0 for a loss
+type TuningEntry struct {
+	Pos    *Position
+	Result float64
+}
+
+// ParseTuningEntry reads a dataset line holding a FEN followed by the game result. The
+// result may be written as "1-0", "0-1" or "1/2-1/2", or as a score like "[0.5]", and may
+// be separated from the FEN by a semicolon, a pipe or an EPD "c9" opcode. FENs without
+// move counters are accepted.
+func ParseTuningEntry(line string) (*TuningEntry, error) {
+	fields := strings.Fields(strings.NewReplacer(";", " ", "|", " ", "\"", " ", "[", " ", "]", " ").Replace(line))
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("expected a fen and a result in %q", line)
+	}
+	result, resultErr := parseTuningResult(fields[len(fields)-1])
+	if resultErr != nil {
+		return nil, resultErr
+	}
+	fenFields := fields[:len(fields)-1]
+	if fenFields[len(fenFields)-1] == "c9" {
+		fenFields = fenFields[:len(fenFields)-1]
+	}
+	if len(fenFields) == 4 {
+		fenFields = append(fenFields, "0", "1")
+	}
+	pos, posErr := FromFEN(strings.Join(fenFields, " "))
+	if posErr != nil {
+		return nil, posErr
+	}
+	return &TuningEntry{pos, result}, nil
+}
+
+func parseTuningResult(token string) (float64, error) {
+	switch token {
+	case "1-0":
+		return 1, nil
+	case "0-1":
+		return 0, nil
+	case "1/2-1/2":
+		return 0.5, nil
+	}
+	result, parseErr := strconv.ParseFloat(token, 64)
+	if parseErr != nil || result < 0 || result > 1 {
+		return 0, fmt.Errorf("could not parse %s as a game result", token)
+	}
+	return result, nil
+}
+
+// LoadTuningDataset reads every entry of a dataset file, one per line. Blank lines and
+// lines starting with '#' are skipped.
+func LoadTuningDataset(path string) ([]TuningEntry, error) {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	entries := make([]TuningEntry, 0)
+	scanner := bufio.NewScanner(file)
+	for lineIdx := 1; scanner.Scan(); lineIdx++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, parseErr := ParseTuningEntry(line)
+		if parseErr != nil {
+			return nil, fmt.Errorf("line %d: %w", lineIdx, parseErr)
+		}
+		entries = append(entries, *entry)
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, scanErr
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no positions found in %s", path)
+	}
+	return entries, nil
+}
+
+// WinProbability maps an evaluation from white's point of view to white's expected score,
+// with k scaling how quickly centipawns turn into wins
+func WinProbability(eval int16, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*float64(eval)/400))
+}
+
+// Tuner fits the evaluation params to a dataset with Texel's method: the error is the
+// mean squared difference between the game results and the win probabilities predicted
+// by EvalPos, and each weight is nudged in turn for as long as that lowers the error
+type Tuner struct {
+	Entries []TuningEntry
+	// K is the scaling of WinProbability, fit once to the starting params so that the
+	// tuning changes the weights rather than the scale of the whole evaluation
+	K       float64
+	Threads int
+	// Step is how much a weight is nudged at a time
+	Step      int16
+	MaxPasses int
+}
+
+func NewTuner(entries []TuningEntry, threads int, params *EvalParams) *Tuner {
+	t := &Tuner{
+		Entries:   entries,
+		Threads:   MaxInt(1, threads),
+		Step:      1,
+		MaxPasses: 100,
+	}
+	t.K = t.fitK(params)
+	return t
+}
+
+// Error is the mean squared error of the params over the dataset. The evaluation reads the
+// global params, so they are swapped in for the duration.
+func (t *Tuner) Error(params *EvalParams) float64 {
+	lastParams := evalParams
+	evalParams = params
+	defer func() { evalParams = lastParams }()
+	return t.errorWithK(t.K)
+}
+
+func (t *Tuner) errorWithK(k float64) float64 {
+	const CHUNK_SIZE = 1024
+	var nextChunkIdx atomic.Int64
+	sums := make([]float64, t.Threads)
+	wg := sync.WaitGroup{}
+	for thread := 0; thread < t.Threads; thread++ {
+		wg.Add(1)
+		go func(thread int) {
+			defer wg.Done()
+			for {
+				start := int(nextChunkIdx.Add(1)-1) * CHUNK_SIZE
+				if start >= len(t.Entries) {
+					return
+				}
+				for _, entry := range t.Entries[start:MinInt(start+CHUNK_SIZE, len(t.Entries))] {
+					eval := EvalPos(entry.Pos)
+					if !entry.Pos.isWhiteTurn {
+						eval = -eval
+					}
+					diff := entry.Result - WinProbability(eval, k)
+					sums[thread] += diff * diff
+				}
+			}
+		}(thread)
+	}
+	wg.Wait()
+	var sum float64
+	for _, threadSum := range sums {
+		sum += threadSum
+	}
+	return sum / float64(len(t.Entries))
+}
+
+// fitK finds the scaling that best fits the params to the dataset, with a coarse scan that
+// is refined around the best value found
+func (t *Tuner) fitK(params *EvalParams) float64 {
+	lastParams := evalParams
+	evalParams = params
+	defer func() { evalParams = lastParams }()
+
+	bestK, bestErr := 1.0, t.errorWithK(1.0)
+	for _, step := range []float64{0.1, 0.01, 0.001} {
+		center := bestK
+		for k := center - 10*step; k <= center+10*step; k += step {
+			if k <= 0 {
+				continue
+			}
+			if err := t.errorWithK(k); err < bestErr {
+				bestK, bestErr = k, err
+			}
+		}
+	}
+	return bestK
+}
+
+// Tune runs passes over every tunable weight of the params, trying to raise and then lower
+// each by the step and keeping whichever change lowers the error. It stops after a pass
+// that improves nothing, or after the maximum number of passes. The params are tuned in
+// place, and onPass, which may be nil, is called with the error after each pass.
+func (t *Tuner) Tune(params *EvalParams, onPass func(pass int, err float64)) float64 {
+	bestErr := t.Error(params)
+	for pass := 1; pass <= t.MaxPasses; pass++ {
+		isImproved := false
+		for _, param := range params.Vector() {
+			if !param.IsTunable {
+				continue
+			}
+			startValue := *param.Value
+			for _, delta := range []int16{t.Step, -t.Step} {
+				*param.Value = startValue + delta
+				if err := t.Error(params); err < bestErr {
+					bestErr = err
+					isImproved = true
+					break
+				}
+				*param.Value = startValue
+			}
+		}
+		if onPass != nil {
+			onPass(pass, bestErr)
+		}
+		if !isImproved {
+			break
+		}
+	}
+	return bestErr
+}
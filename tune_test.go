@@ -0,0 +1,87 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tuning", func() {
+	Describe("#ParseTuningEntry", func() {
+		It("reads the result in any of the common formats", func() {
+			for line, result := range map[string]float64{
+				"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 [1.0]":       1,
+				"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1; 0-1":        0,
+				"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 | 1/2-1/2":   0.5,
+				"4k3/8/8/8/8/8/4P3/4K3 w - - c9 \"1-0\";":     1,
+				"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 \"1/2-1/2\"": 0.5,
+				"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 0.5":         0.5,
+			} {
+				entry, parseErr := ParseTuningEntry(line)
+				Expect(parseErr).ToNot(HaveOccurred(), line)
+				Expect(entry.Result).To(Equal(result), line)
+				Expect(entry.Pos.FEN()).To(Equal("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"))
+			}
+		})
+		It("rejects lines without a result", func() {
+			_, parseErr := ParseTuningEntry("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+			Expect(parseErr).To(HaveOccurred())
+			_, parseErr = ParseTuningEntry("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 [2.0]")
+			Expect(parseErr).To(HaveOccurred())
+		})
+	})
+	Describe("#WinProbability", func() {
+		It("is even at zero and grows with the evaluation", func() {
+			Expect(WinProbability(0, 1)).To(Equal(0.5))
+			Expect(WinProbability(400, 1)).To(BeNumerically("~", 10.0/11, 1e-9))
+			Expect(WinProbability(-400, 1)).To(BeNumerically("~", 1.0/11, 1e-9))
+		})
+	})
+	Describe("Tuner", func() {
+		var entries []TuningEntry
+		BeforeEach(func() {
+			entries = make([]TuningEntry, 0)
+			for _, line := range []string{
+				"4k3/8/8/8/8/8/PPP5/4K3 w - - 0 1 [1.0]",
+				"4k3/8/8/8/8/8/PP6/4K3 w - - 0 1 [1.0]",
+				"4k3/ppp5/8/8/8/8/8/4K3 b - - 0 1 [0.0]",
+				"4k3/8/8/8/8/8/8/3NK3 w - - 0 1 [0.5]",
+				"3nk3/8/8/8/8/8/8/4K3 w - - 0 1 [0.5]",
+				"4k3/8/8/8/8/8/8/3RK3 w - - 0 1 [1.0]",
+			} {
+				entry, parseErr := ParseTuningEntry(line)
+				Expect(parseErr).ToNot(HaveOccurred())
+				entries = append(entries, *entry)
+			}
+		})
+		It("fits K to the starting params", func() {
+			params := DefaultEvalParams()
+			tuner := NewTuner(entries, 2, params)
+			Expect(tuner.K).To(BeNumerically(">", 0))
+			for _, k := range []float64{tuner.K / 2, tuner.K * 2} {
+				Expect(tuner.errorWithK(k)).To(BeNumerically(">=", tuner.Error(params)))
+			}
+		})
+		It("computes the same error on any number of threads", func() {
+			params := DefaultEvalParams()
+			tuner := NewTuner(entries, 1, params)
+			singleThreaded := tuner.Error(params)
+			tuner.Threads = 4
+			Expect(tuner.Error(params)).To(BeNumerically("~", singleThreaded, 1e-12))
+		})
+		It("lowers the error without touching the params in use", func() {
+			params := DefaultEvalParams()
+			tuner := NewTuner(entries, 2, params)
+			tuner.MaxPasses = 3
+			startErr := tuner.Error(params)
+			passes := 0
+			endErr := tuner.Tune(params, func(pass int, err float64) {
+				passes = pass
+			})
+			Expect(endErr).To(BeNumerically("<", startErr))
+			Expect(passes).To(BeNumerically(">", 0))
+			Expect(tuner.Error(params)).To(Equal(endErr))
+			Expect(*evalParams).To(Equal(*DefaultEvalParams()))
+			Expect(params.DrawVal).To(Equal(DRAW_VAL))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"github.com/CameronHonis/chess"
 	"os"
@@ -12,12 +13,23 @@ import (
 type Uci struct {
 	pos *Position
 	tt  *TranspTable
+
+	multiPV     int
+	threads     int
+	lmrStrength int
+
+	search     *Search
+	searchDone chan struct{}
+	isQuitting bool
 }
 
 func NewUci(tt *TranspTable) *Uci {
 	return &Uci{
-		pos: InitPos(),
-		tt:  tt,
+		pos:         InitPos(),
+		tt:          tt,
+		multiPV:     1,
+		threads:     1,
+		lmrStrength: DEFAULT_LMR_STRENGTH,
 	}
 }
 
@@ -26,16 +38,42 @@ func (uci *Uci) Start() {
 	for scanner.Scan() {
 		line := scanner.Text()
 		uci.handleInput(line)
+		if uci.isQuitting {
+			return
+		}
 	}
 	if err := scanner.Err(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, "reading stdin: ", err)
 	}
+	uci.finishSearch()
+}
+
+// finishSearch waits for the running search, if any, to report its best move. Infinite and
+// ponder searches are stopped instead, as they only end on a "stop" or "ponderhit" that
+// will never come once the input is closed.
+func (uci *Uci) finishSearch() {
+	if uci.search == nil {
+		return
+	}
+	if uci.search.Constraints.infinite || uci.search.IsPondering() {
+		uci.stopSearch()
+		return
+	}
+	<-uci.searchDone
+	uci.search = nil
+	uci.searchDone = nil
 }
 
 func (uci *Uci) handleInput(s string) {
 	toks := strings.Split(s, " ")
 	cmd := toks[0]
 	if cmd == "uci" {
+		fmt.Printf("option name Hash type spin default %d min %d max %d\n", DEFAULT_HASH_MB, MIN_HASH_MB, MAX_HASH_MB)
+		fmt.Println("option name Ponder type check default false")
+		fmt.Printf("option name MultiPV type spin default 1 min 1 max %d\n", MAX_MULTI_PV)
+		fmt.Printf("option name Threads type spin default 1 min 1 max %d\n", MAX_THREADS)
+		fmt.Printf("option name LMRStrength type spin default %d min 1 max %d\n", DEFAULT_LMR_STRENGTH, MAX_LMR_STRENGTH)
+		fmt.Println("option name EvalFile type string default <empty>")
 		fmt.Println("uciok")
 	} else if cmd == "position" {
 		pos, err := handlePositionCmd(toks)
@@ -45,13 +83,51 @@ func (uci *Uci) handleInput(s string) {
 			fmt.Println("set position to", pos.FEN())
 			uci.pos = pos
 		}
+	} else if cmd == "go" && len(toks) > 1 && toks[1] == "perft" {
+		depth, hashMb, err := handlePerftCmd(toks)
+		if err != nil {
+			fmt.Println(err)
+		} else if depth >= 0 {
+			uci.stopSearch()
+			var table *PerftTable
+			if hashMb > 0 {
+				table = NewPerftTable(hashMb)
+			}
+			RunPerft(uci.pos, depth, uci.threads, table)
+		}
 	} else if cmd == "go" {
 		constraints, err := handleGoCmd(toks, uci.pos)
 		if err != nil {
 			fmt.Println(err)
 		} else if constraints != nil {
 			fmt.Println("starting search")
-			go NewSearch(uci.pos, constraints, uci.tt).Start()
+			uci.startSearch(constraints)
+		}
+	} else if cmd == "eval" {
+		isJSON, isHelp, err := handleEvalCmd(toks)
+		if err != nil {
+			fmt.Println(err)
+		} else if !isHelp {
+			uci.stopSearch()
+			uci.printEval(isJSON)
+		}
+	} else if cmd == "ponderhit" {
+		if uci.search != nil {
+			uci.search.PonderHit()
+		}
+	} else if cmd == "stop" {
+		uci.stopSearch()
+	} else if cmd == "quit" {
+		uci.stopSearch()
+		uci.isQuitting = true
+	} else if cmd == "setoption" {
+		name, value, err := handleSetOptionCmd(toks)
+		if err != nil {
+			fmt.Println(err)
+		} else if name != "" {
+			if err = uci.setOption(name, value); err != nil {
+				fmt.Println(err)
+			}
 		}
 	} else if cmd == "isready" {
 		fmt.Println("readyok")
@@ -60,6 +136,160 @@ func (uci *Uci) handleInput(s string) {
 	}
 }
 
+// startSearch runs a search in the background, halting any search already running
+func (uci *Uci) startSearch(constraints *SearchConstraints) {
+	uci.stopSearch()
+	constraints.multiPV = uci.multiPV
+	constraints.threads = uci.threads
+	constraints.lmrStrength = uci.lmrStrength
+	search := NewSearch(uci.pos, constraints, uci.tt)
+	searchDone := make(chan struct{})
+	uci.search = search
+	uci.searchDone = searchDone
+	go func() {
+		search.Start()
+		close(searchDone)
+	}()
+}
+
+// printEval prints the breakdown of the static evaluation of the current position
+func (uci *Uci) printEval(isJSON bool) {
+	breakdown := NewEvalBreakdown(uci.pos)
+	if !isJSON {
+		fmt.Print(breakdown)
+		return
+	}
+	out, err := json.Marshal(breakdown)
+	if err != nil {
+		fmt.Println("could not encode eval:", err)
+		return
+	}
+	fmt.Println(string(out))
+}
+
+// stopSearch halts the running search, if any, and blocks until it has reported its best move
+func (uci *Uci) stopSearch() {
+	if uci.search == nil {
+		return
+	}
+	uci.search.Stop()
+	<-uci.searchDone
+	uci.search = nil
+	uci.searchDone = nil
+}
+
+func (uci *Uci) setOption(name, value string) error {
+	if strings.EqualFold(name, "Hash") {
+		sizeMb, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return fmt.Errorf("could not parse %s as Hash: %s", value, parseErr)
+		}
+		if sizeMb < MIN_HASH_MB || sizeMb > MAX_HASH_MB {
+			return fmt.Errorf("Hash %d out of range [%d, %d]", sizeMb, MIN_HASH_MB, MAX_HASH_MB)
+		}
+		// the search threads index the table without locking, so it can't change under them
+		uci.stopSearch()
+		uci.tt.Resize(sizeMb)
+		fmt.Println("set Hash to", sizeMb)
+	} else if strings.EqualFold(name, "MultiPV") {
+		multiPV, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return fmt.Errorf("could not parse %s as MultiPV: %s", value, parseErr)
+		}
+		if multiPV < 1 || multiPV > MAX_MULTI_PV {
+			return fmt.Errorf("MultiPV %d out of range [1, %d]", multiPV, MAX_MULTI_PV)
+		}
+		uci.multiPV = multiPV
+		fmt.Println("set MultiPV to", multiPV)
+	} else if strings.EqualFold(name, "Threads") {
+		threads, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return fmt.Errorf("could not parse %s as Threads: %s", value, parseErr)
+		}
+		if threads < 1 || threads > MAX_THREADS {
+			return fmt.Errorf("Threads %d out of range [1, %d]", threads, MAX_THREADS)
+		}
+		uci.threads = threads
+		fmt.Println("set Threads to", threads)
+	} else if strings.EqualFold(name, "LMRStrength") {
+		lmrStrength, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return fmt.Errorf("could not parse %s as LMRStrength: %s", value, parseErr)
+		}
+		if lmrStrength < 1 || lmrStrength > MAX_LMR_STRENGTH {
+			return fmt.Errorf("LMRStrength %d out of range [1, %d]", lmrStrength, MAX_LMR_STRENGTH)
+		}
+		uci.lmrStrength = lmrStrength
+		fmt.Println("set LMRStrength to", lmrStrength)
+	} else if strings.EqualFold(name, "EvalFile") {
+		params := DefaultEvalParams()
+		if value != "" && value != "<empty>" {
+			var loadErr error
+			if params, loadErr = LoadEvalParams(value); loadErr != nil {
+				return loadErr
+			}
+		}
+		uci.stopSearch()
+		SetEvalParams(params)
+		// scores cached under the old params would mix with the new ones
+		uci.tt.Clear()
+		fmt.Println("set EvalFile to", value)
+	} else if strings.EqualFold(name, "Ponder") {
+		// pondering is driven entirely by the GUI through "go ponder", nothing to configure
+	} else {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	return nil
+}
+
+func handleSetOptionCmd(toks []string) (name string, value string, err error) {
+	if len(toks) < 2 || toks[1] == "--help" || toks[1] == "help" {
+		printSetOptionCmdHelp()
+		return "", "", nil
+	}
+	if toks[1] != "name" {
+		return "", "", fmt.Errorf("expected name argument, got %s", toks[1])
+	}
+	nameToks := make([]string, 0)
+	valueToks := make([]string, 0)
+	var isValueToks = false
+	for _, tok := range toks[2:] {
+		if tok == "value" && !isValueToks {
+			isValueToks = true
+			continue
+		}
+		if isValueToks {
+			valueToks = append(valueToks, tok)
+		} else {
+			nameToks = append(nameToks, tok)
+		}
+	}
+	if len(nameToks) == 0 {
+		return "", "", fmt.Errorf("missing argument for name")
+	}
+	return strings.Join(nameToks, " "), strings.Join(valueToks, " "), nil
+}
+
+func printSetOptionCmdHelp() {
+	fmt.Println("UCI setoption: change an internal parameter of the engine")
+	fmt.Println("Usage:")
+	fmt.Println("    setoption name {id} [value {x}]")
+	fmt.Println("")
+	fmt.Println("The options are:")
+	fmt.Println(Tabbed(1, Bold("Hash")+" {MB}"))
+	fmt.Println(Tabbed(2, fmt.Sprintf("size of the transposition table in megabytes, defaults to %d", DEFAULT_HASH_MB)))
+	fmt.Println(Tabbed(1, Bold("MultiPV")+" {n}"))
+	fmt.Println(Tabbed(2, "report the best n root moves, each with their own score and line, defaults to 1"))
+	fmt.Println(Tabbed(1, Bold("Threads")+" {n}"))
+	fmt.Println(Tabbed(2, "number of threads searching in parallel, sharing the transposition table, defaults to 1"))
+	fmt.Println(Tabbed(1, Bold("LMRStrength")+" {percent}"))
+	fmt.Println(Tabbed(2, fmt.Sprintf("scales how much shallower late quiet moves are searched, defaults to %d", DEFAULT_LMR_STRENGTH)))
+	fmt.Println(Tabbed(1, Bold("EvalFile")+" {path}"))
+	fmt.Println(Tabbed(2, "JSON file of eval params, as written by the tune command, defaults to the built-in params"))
+	fmt.Println(Tabbed(1, Bold("Ponder")+" {true | false}"))
+	fmt.Println(Tabbed(2, "informs the engine that the GUI may send \"go ponder\", has no effect on the search"))
+}
+
 func handlePositionCmd(toks []string) (*Position, error) {
 	if len(toks) < 2 || toks[1] == "--help" || toks[1] == "help" {
 		printPositionCmdHelp()
@@ -141,6 +371,9 @@ func handleGoCmd(toks []string, pos *Position) (*SearchConstraints, error) {
 			var moveIdx = 0
 			for ; moveIdx+tokIdx+1 < len(toks); moveIdx++ {
 				moveStr := toks[moveIdx+tokIdx+1]
+				if isGoCmdArg(moveStr) {
+					break
+				}
 				board, boardErr := chess.BoardFromFEN(pos.FEN())
 				if boardErr != nil {
 					return nil, fmt.Errorf("could not convert from pos to board: %s", boardErr)
@@ -225,6 +458,12 @@ func handleGoCmd(toks []string, pos *Position) (*SearchConstraints, error) {
 			}
 			opts.maxNodes = nodes
 			tokIdx += 2
+		} else if currTok == "infinite" {
+			opts.infinite = true
+			tokIdx++
+		} else if currTok == "ponder" {
+			opts.ponder = true
+			tokIdx++
 		} else if currTok == "movetime" {
 			if tokIdx+1 >= len(toks) {
 				return nil, fmt.Errorf("missing argument for movetime")
@@ -243,6 +482,94 @@ func handleGoCmd(toks []string, pos *Position) (*SearchConstraints, error) {
 	return opts, nil
 }
 
+// handlePerftCmd parses "go perft {depth} [hash {MB}]". The depth is negative if only help
+// was requested.
+func handlePerftCmd(toks []string) (depth int, hashMb int, err error) {
+	if len(toks) < 3 || toks[2] == "--help" || toks[2] == "help" {
+		printPerftCmdHelp()
+		return -1, 0, nil
+	}
+	depth, parseErr := strconv.Atoi(toks[2])
+	if parseErr != nil || depth < 0 {
+		return 0, 0, fmt.Errorf("could not parse %s as perft depth", toks[2])
+	}
+	var tokIdx = 3
+	for tokIdx < len(toks) {
+		currTok := toks[tokIdx]
+		if currTok == "hash" {
+			if tokIdx+1 >= len(toks) {
+				return 0, 0, fmt.Errorf("missing argument for hash")
+			}
+			hashMbStr := toks[tokIdx+1]
+			hashMb, parseErr = strconv.Atoi(hashMbStr)
+			if parseErr != nil {
+				return 0, 0, fmt.Errorf("could not parse %s as hash: %s", hashMbStr, parseErr)
+			}
+			if hashMb < 0 || hashMb > MAX_HASH_MB {
+				return 0, 0, fmt.Errorf("hash %d out of range [0, %d]", hashMb, MAX_HASH_MB)
+			}
+			tokIdx += 2
+		} else {
+			return 0, 0, fmt.Errorf("unknown argument: %s", currTok)
+		}
+	}
+	return depth, hashMb, nil
+}
+
+func printPerftCmdHelp() {
+	fmt.Println("go perft: count the leaf nodes of the legal move tree from the current internal position")
+	fmt.Println("Usage:")
+	fmt.Println("    go perft {depth} [hash {MB}]")
+	fmt.Println("")
+	fmt.Println("The node count under each root move is printed, followed by the total.")
+	fmt.Println("The root moves are split across the threads set by the Threads option.")
+	fmt.Println("The arguments are:")
+	fmt.Println(Tabbed(1, Bold("hash")+" {MB}"))
+	fmt.Println(Tabbed(2, "cache the counts of transposed positions in a table of this size, off by default"))
+}
+
+// handleEvalCmd parses "eval [json]"
+func handleEvalCmd(toks []string) (isJSON bool, isHelp bool, err error) {
+	if len(toks) < 2 || toks[1] == "" {
+		return false, false, nil
+	}
+	if len(toks) > 2 {
+		return false, false, fmt.Errorf("unknown argument: %s", toks[2])
+	}
+	switch toks[1] {
+	case "json":
+		return true, false, nil
+	case "--help", "help":
+		printEvalCmdHelp()
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("unknown argument: %s", toks[1])
+	}
+}
+
+func printEvalCmdHelp() {
+	fmt.Println("eval: print the static evaluation of the current internal position, term by term")
+	fmt.Println("Usage:")
+	fmt.Println("    eval [json]")
+	fmt.Println("")
+	fmt.Println("Each term is scored for white and black from their own point of view, and for both")
+	fmt.Println("combined from white's point of view, in the middlegame and in the endgame. The final")
+	fmt.Println("eval blends the two by the game phase.")
+	fmt.Println("The arguments are:")
+	fmt.Println(Tabbed(1, Bold("json")))
+	fmt.Println(Tabbed(2, "print the breakdown as a single line of JSON instead of a table"))
+}
+
+// isGoCmdArg returns true if the token starts a new argument of the go command,
+// which ends the list of moves following searchmoves
+func isGoCmdArg(tok string) bool {
+	switch tok {
+	case "searchmoves", "wtime", "btime", "winc", "binc", "depth", "nodes", "movetime", "infinite", "ponder":
+		return true
+	}
+	return false
+}
+
 func printGoCmdHelp() {
 	fmt.Println("UCI go: start search on the current internal position")
 	fmt.Println("Usage:")
@@ -269,4 +596,11 @@ func printGoCmdHelp() {
 	fmt.Println(Tabbed(2, "limits the search only x nodes"))
 	fmt.Println(Tabbed(1, Bold("movetime")+" {mSec}"))
 	fmt.Println(Tabbed(2, "requires the search to last exactly x msec"))
+	fmt.Println(Tabbed(1, Bold("infinite")))
+	fmt.Println(Tabbed(2, "search until the stop command, the best move is not reported before then"))
+	fmt.Println(Tabbed(1, Bold("ponder")))
+	fmt.Println(Tabbed(2, "search the current position, assumed to follow the expected reply, on the opponent's time"))
+	fmt.Println(Tabbed(2, "the clock starts on ponderhit, and the best move is not reported before ponderhit or stop"))
+	fmt.Println(Tabbed(1, Bold("perft")+" {depth}"))
+	fmt.Println(Tabbed(2, "count the leaf nodes of the legal move tree instead of searching, see `go perft help`"))
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"path/filepath"
+	"strings"
+)
+
+var _ = Describe("Uci", func() {
+	Describe("::setOption", func() {
+		var uci *Uci
+		BeforeEach(func() {
+			uci = NewUci(NewTranspTable(MIN_HASH_MB))
+		})
+		It("stops the running search before resizing the table", func() {
+			uci.threads = 4
+			DeferCleanup(uci.stopSearch)
+			uci.startSearch(&SearchConstraints{infinite: true})
+			Expect(uci.setOption("Hash", fmt.Sprint(MIN_HASH_MB*4))).To(Succeed())
+			Expect(uci.search).To(BeNil())
+			Expect(uci.tt.buckets).To(HaveLen(int(uci.tt.mask + 1)))
+			uci.startSearch(&SearchConstraints{maxDepth: 4})
+			<-uci.searchDone
+			uci.stopSearch()
+		})
+		It("passes LMRStrength on to the next search", func() {
+			Expect(uci.setOption("LMRStrength", "150")).To(Succeed())
+			uci.startSearch(&SearchConstraints{maxDepth: 1})
+			Expect(uci.search.Constraints.LMRStrength()).To(Equal(150))
+			uci.stopSearch()
+		})
+		It("rejects an LMRStrength out of range", func() {
+			Expect(uci.setOption("LMRStrength", "0")).ToNot(Succeed())
+			Expect(uci.setOption("LMRStrength", fmt.Sprint(MAX_LMR_STRENGTH+1))).ToNot(Succeed())
+			Expect(uci.lmrStrength).To(Equal(DEFAULT_LMR_STRENGTH))
+		})
+		It("swaps in the eval params of an EvalFile, and back to the defaults", func() {
+			DeferCleanup(SetEvalParams, DefaultEvalParams())
+			params := DefaultEvalParams()
+			params.DrawVal = 0
+			path := filepath.Join(GinkgoT().TempDir(), "params.json")
+			Expect(SaveEvalParams(path, params)).To(Succeed())
+
+			uci.startSearch(&SearchConstraints{infinite: true})
+			Expect(uci.setOption("EvalFile", path)).To(Succeed())
+			Expect(uci.search).To(BeNil())
+			Expect(evalParams.DrawVal).To(Equal(int16(0)))
+			Expect(uci.setOption("EvalFile", "<empty>")).To(Succeed())
+			Expect(*evalParams).To(Equal(*DefaultEvalParams()))
+		})
+		It("keeps the eval params when an EvalFile can't be read", func() {
+			Expect(uci.setOption("EvalFile", filepath.Join(GinkgoT().TempDir(), "missing.json"))).ToNot(Succeed())
+			Expect(*evalParams).To(Equal(*DefaultEvalParams()))
+		})
+	})
+	Describe("::finishSearch", func() {
+		var uci *Uci
+		BeforeEach(func() {
+			uci = NewUci(NewTranspTable(MIN_HASH_MB))
+		})
+		It("stops an infinite search", func() {
+			uci.startSearch(&SearchConstraints{infinite: true})
+			done := make(chan struct{})
+			go func() {
+				uci.finishSearch()
+				close(done)
+			}()
+			Eventually(done, "1s").Should(BeClosed())
+			Expect(uci.search).To(BeNil())
+		})
+		It("lets a limited search run to the end", func() {
+			uci.startSearch(&SearchConstraints{maxDepth: 4})
+			search := uci.search
+			uci.finishSearch()
+			Expect(uci.search).To(BeNil())
+			Expect(search.lastResult.Load().depth).To(Equal(uint8(4)))
+		})
+	})
+	Describe("#handlePerftCmd", func() {
+		It("parses the depth and hash size", func() {
+			depth, hashMb, err := handlePerftCmd(strings.Split("go perft 5 hash 16", " "))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(depth).To(Equal(5))
+			Expect(hashMb).To(Equal(16))
+		})
+		It("rejects a negative depth", func() {
+			_, _, err := handlePerftCmd(strings.Split("go perft -1", " "))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("#handleEvalCmd", func() {
+		It("prints a table by default", func() {
+			isJSON, isHelp, err := handleEvalCmd([]string{"eval"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isJSON).To(BeFalse())
+			Expect(isHelp).To(BeFalse())
+		})
+		It("parses the json argument", func() {
+			isJSON, _, err := handleEvalCmd(strings.Split("eval json", " "))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isJSON).To(BeTrue())
+		})
+		It("rejects unknown arguments", func() {
+			_, _, err := handleEvalCmd(strings.Split("eval yaml", " "))
+			Expect(err).To(HaveOccurred())
+			_, _, err = handleEvalCmd(strings.Split("eval json extra", " "))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("#handleGoCmd", func() {
+		When("searchmoves is followed by another argument", func() {
+			It("stops parsing moves at the argument", func() {
+				constraints, err := handleGoCmd(strings.Split("go searchmoves e2e4 d2d4 depth 5", " "), InitPos())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(constraints.moves).To(Equal([]Move{NewNormalMove(SQ_E2, SQ_E4), NewNormalMove(SQ_D2, SQ_D4)}))
+				Expect(constraints.maxDepth).To(Equal(uint8(5)))
+			})
+		})
+		When("a searchmove is illegal", func() {
+			It("returns an error", func() {
+				_, err := handleGoCmd(strings.Split("go searchmoves e2e5", " "), InitPos())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
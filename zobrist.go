@@ -104,6 +104,20 @@ func NewZHash(pos *Position) ZHash {
 	return hash
 }
 
+// NewPawnZHash hashes only the pawns of the position, so that positions with the same
+// pawn structure share a key
+func NewPawnZHash(pos *Position) ZHash {
+	var hash ZHash
+	for _, pawn := range []Piece{W_PAWN, B_PAWN} {
+		for pawnsLeft := pos.pieceBitboards[pawn]; pawnsLeft != 0; {
+			var sq Square
+			sq, pawnsLeft = pawnsLeft.PopFirstSq()
+			hash ^= lookups.PieceKeys[sq][pawn-1]
+		}
+	}
+	return hash
+}
+
 func (zh ZHash) UpdatePieceOnSq(prevPiece, piece Piece, sq Square) ZHash {
 	if prevPiece != EMPTY {
 		zh ^= lookups.PieceKeys[sq][prevPiece-1]